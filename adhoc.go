@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+)
+
+// adHocLoad type-checks filename, together with the files in its
+// directory that belong to the same package (including its tests if
+// filename is a test), as a package of its own.
+// It is used for files that the go command cannot load, such as scratch
+// files outside any module.  Imports are type-checked from source found
+// in GOROOT, the enclosing module and workspace, the module cache and
+// GOPATH, and type errors are ignored.
+func adHocLoad(bc *BuildContext, filename string, start, end int, overlay map[string][]byte) (*packages.Package, []ast.Node, error) {
+	fset := token.NewFileSet()
+	src, err := readSource(filename, overlay)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if file == nil {
+		return nil, nil, err
+	}
+	path, keep, err := cursorPath(filename, file, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	stripBodies(file, keep)
+
+	pkg := &packages.Package{
+		ID:      "command-line-arguments",
+		Name:    file.Name.Name,
+		PkgPath: "command-line-arguments",
+		GoFiles: []string{filename},
+		Fset:    fset,
+		Syntax:  []*ast.File{file},
+		Imports: make(map[string]*packages.Package),
+	}
+	dir := filepath.Dir(filename)
+	siblings, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for name := range overlay {
+		// unsaved files are siblings too
+		if filepath.Dir(name) == dir && strings.HasSuffix(name, ".go") {
+			if _, err := os.Stat(name); err != nil {
+				siblings = append(siblings, name)
+			}
+		}
+	}
+	isTest := strings.HasSuffix(filename, "_test.go")
+	for _, name := range siblings {
+		// tests belong to the package only when checking a test file
+		if name == filename || !isTest && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := readSource(name, overlay)
+		if err != nil || !bc.matches(name, src) {
+			continue
+		}
+		f, _ := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if f == nil || f.Name.Name != file.Name.Name {
+			continue
+		}
+		stripBodies(f, nil)
+		pkg.GoFiles = append(pkg.GoFiles, name)
+		pkg.Syntax = append(pkg.Syntax, f)
+	}
+
+	imp := &sourceImporter{
+		fset: fset,
+		ctxt: bc.context("", nil),
+		pkgs: make(map[string]*packages.Package),
+	}
+	// cgo files can't be type-checked from source
+	imp.ctxt.CgoEnabled = false
+	pkg.TypesInfo = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := imp.config(pkg, dir)
+	conf.Error = func(error) { pkg.IllTyped = true }
+	pkg.Types, _ = conf.Check(pkg.PkgPath, fset, pkg.Syntax, pkg.TypesInfo)
+	return pkg, path, nil
+}
+
+// sourceImporter type-checks imported packages from source, without
+// function bodies.
+type sourceImporter struct {
+	fset *token.FileSet
+	ctxt build.Context
+	pkgs map[string]*packages.Package // by directory
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// config returns the configuration for type-checking pkg, whose files are
+// in dir, recording its imports in pkg.Imports.
+func (imp *sourceImporter) config(pkg *packages.Package, dir string) *types.Config {
+	return &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			dep, err := imp.load(path, dir)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Imports[path] = dep
+			return dep.Types, nil
+		}),
+		Error: func(error) {},
+		Sizes: types.SizesFor("gc", imp.ctxt.GOARCH),
+	}
+}
+
+// load loads the package with the given import path, imported from a
+// package in srcDir.
+func (imp *sourceImporter) load(path, srcDir string) (*packages.Package, error) {
+	if path == "unsafe" {
+		return &packages.Package{ID: path, Name: path, PkgPath: path, Types: types.Unsafe}, nil
+	}
+	dir := findImportDir(path, srcDir)
+	if dir == "" {
+		return nil, fmt.Errorf("cannot find package %q", path)
+	}
+	if pkg, ok := imp.pkgs[dir]; ok {
+		if pkg.Types == nil {
+			return nil, fmt.Errorf("import cycle through %q", path)
+		}
+		return pkg, nil
+	}
+	bp, err := imp.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &packages.Package{
+		ID:      path,
+		Name:    bp.Name,
+		PkgPath: path,
+		Fset:    imp.fset,
+		Imports: make(map[string]*packages.Package),
+	}
+	imp.pkgs[dir] = pkg
+	for _, name := range bp.GoFiles {
+		filename := filepath.Join(dir, name)
+		f, _ := parser.ParseFile(imp.fset, filename, nil, parser.ParseComments)
+		if f == nil {
+			continue
+		}
+		stripBodies(f, nil)
+		pkg.GoFiles = append(pkg.GoFiles, filename)
+		pkg.Syntax = append(pkg.Syntax, f)
+	}
+	pkg.Types, _ = imp.config(pkg, dir).Check(path, imp.fset, pkg.Syntax, nil)
+	return pkg, nil
+}
+
+// findImportDir returns the directory of the package with the given
+// import path, imported from a package in srcDir.  It looks in GOROOT
+// (and its vendor directory for imports from the standard library), the
+// module containing srcDir and the other modules of its workspace, the
+// module cache, and GOPATH.  It returns "" if there is none.
+func findImportDir(path, srcDir string) string {
+	goroot := filepath.Join(build.Default.GOROOT, "src")
+	if dir := filepath.Join(goroot, filepath.FromSlash(path)); isDir(dir) {
+		return dir
+	}
+	if hasPathPrefix(srcDir, goroot) {
+		if dir := filepath.Join(goroot, "vendor", filepath.FromSlash(path)); isDir(dir) {
+			return dir
+		}
+	}
+	if dir := localModuleDir(path, srcDir); dir != "" {
+		return dir
+	}
+	if dir := cachedModuleDir(path, srcDir); dir != "" {
+		return dir
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		gopath = build.Default.GOPATH
+	}
+	for _, root := range filepath.SplitList(gopath) {
+		if dir := filepath.Join(root, "src", filepath.FromSlash(path)); isDir(dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// localModuleDir returns the directory of the package with the given
+// import path in the module containing srcDir or another module of its
+// go.work workspace, or "" if it is in none of them.
+func localModuleDir(path, srcDir string) string {
+	var mods []*goMod
+	if gomod := findGoMod(srcDir); gomod != "" {
+		if mod, err := parseGoMod(gomod); err == nil {
+			mods = append(mods, mod)
+		}
+	}
+	if gowork := findGoWork(srcDir); gowork != "" {
+		if ws, err := parseGoMod(gowork); err == nil {
+			for _, use := range ws.Use {
+				if !filepath.IsAbs(use) {
+					use = filepath.Join(ws.Dir, use)
+				}
+				if mod, err := parseGoMod(filepath.Join(use, "go.mod")); err == nil {
+					mods = append(mods, mod)
+				}
+			}
+		}
+	}
+	// the longest module path wins, as for nested modules
+	var best *goMod
+	for _, mod := range mods {
+		if mod.Module != "" && hasPathPrefix(path, mod.Module) && (best == nil || len(mod.Module) > len(best.Module)) {
+			best = mod
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	dir := filepath.Join(best.Dir, filepath.FromSlash(strings.TrimPrefix(path, best.Module)))
+	if !isDir(dir) {
+		return ""
+	}
+	return dir
+}
+
+// cachedModuleDir returns the directory of the package with the given
+// import path, imported from a package in srcDir, in the module cache.  It
+// prefers the version of the module providing it that the go.mod file of
+// srcDir requires, then its highest release, then its highest prerelease.
+// It returns "" if there is none.
+func cachedModuleDir(path, srcDir string) string {
+	cache := modCacheDir()
+	if cache == "" {
+		return ""
+	}
+	var require map[string]string
+	if gomod := findGoMod(srcDir); gomod != "" {
+		if mod, err := parseGoMod(gomod); err == nil {
+			require = mod.Require
+		}
+	}
+	elems := strings.Split(path, "/")
+	for i := len(elems); i > 0; i-- {
+		mod := strings.Join(elems[:i], "/")
+		prefix := filepath.Join(cache, filepath.FromSlash(escapeModulePath(mod))) + "@"
+		var versions []string
+		if v, ok := require[mod]; ok {
+			versions = append(versions, v)
+		}
+		dirs, _ := filepath.Glob(prefix + "*")
+		var all []string
+		for _, dir := range dirs {
+			all = append(all, moduleVersion(dir))
+		}
+		if v := latestVersion(all); v != "" {
+			versions = append(versions, v)
+		}
+		for _, v := range versions {
+			if dir := filepath.Join(append([]string{prefix + v}, elems[i:]...)...); isDir(dir) {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+// latestVersion returns the highest release among versions, or else the
+// highest prerelease, or "" if there are none.
+func latestVersion(versions []string) string {
+	release, prerelease := "", ""
+	for _, v := range versions {
+		switch {
+		case !semver.IsValid(v):
+		case semver.Prerelease(v) != "":
+			if prerelease == "" || semver.Compare(v, prerelease) > 0 {
+				prerelease = v
+			}
+		case release == "" || semver.Compare(v, release) > 0:
+			release = v
+		}
+	}
+	if release != "" {
+		return release
+	}
+	return prerelease
+}
+
+// moduleVersion returns the version of the module cache directory dir,
+// as in example.com/mod@v1.2.3.
+func moduleVersion(dir string) string {
+	return dir[strings.LastIndex(dir, "@")+1:]
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
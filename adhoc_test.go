@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdHocFile(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+	a := "package main\n\nimport (\n\t\"strings\"\n\n\t\"example.com/Lib\"\n)\n\nfunc main() {\n\tprintln(strings.ToUpper(greeting()), lib.V)\n}\n"
+	writeTestFile(t, filepath.Join(dir, "a.go"), a)
+	writeTestFile(t, filepath.Join(dir, "b.go"), "package main\n\n// greeting returns a greeting.\nfunc greeting() string { return \"hi\" }\n")
+	writeTestFile(t, filepath.Join(dir, "c.go"), "package other\n\n// greeting is in another package.\nfunc greeting() string { return \"\" }\n")
+	for name, src := range map[string]string{
+		"example.com/!lib@v1.0.0/lib.go":       "package lib\n\n// V is old.\nconst V = 1\n",
+		"example.com/!lib@v1.2.0/lib.go":       "package lib\n\n// V is new.\nconst V = 2\n",
+		"example.com/!lib@v1.10.0-rc.1/lib.go": "package lib\n\n// V is a pre-release.\nconst V = 3\n",
+	} {
+		writeTestFile(t, filepath.Join(cache, filepath.FromSlash(name)), src)
+	}
+	t.Setenv("GOMODCACHE", cache)
+
+	for _, test := range []struct {
+		at, pkg, doc string
+	}{
+		{"ToUpper", "strings", "ToUpper returns"},
+		{"greeting", "main", "greeting returns a greeting.\n"},
+		// the highest release, not the prerelease
+		{"V)", "lib", "V is new.\n"},
+	} {
+		d, err := Run(filepath.Join(dir, "a.go"), strings.Index(a, test.at), nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.at, err)
+			continue
+		}
+		if d.Pkg != test.pkg {
+			t.Errorf("%s: want package %q, got %q", test.at, test.pkg, d.Pkg)
+		}
+		if !strings.HasPrefix(d.Doc, test.doc) {
+			t.Errorf("%s: want doc %q, got %q", test.at, test.doc, d.Doc)
+		}
+	}
+}
+
+func TestAdHocTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := "package main\n\nfunc main() {\n\thelper()\n}\n"
+	aTest := "package main\n\n// helper is only in tests.\nfunc helper() {\n\tgreeting()\n}\n"
+	writeTestFile(t, filepath.Join(dir, "a.go"), a)
+	writeTestFile(t, filepath.Join(dir, "a_test.go"), aTest)
+	writeTestFile(t, filepath.Join(dir, "b.go"), "package main\n\n// greeting returns a greeting.\nfunc greeting() string { return \"hi\" }\n")
+
+	// the tests are not part of the package of a.go
+	if d, err := Run(filepath.Join(dir, "a.go"), strings.Index(a, "helper"), nil); err == nil {
+		t.Errorf("want no documentation for helper, got %q", d.Doc)
+	}
+	// but the package of a test includes the other files
+	d, err := Run(filepath.Join(dir, "a_test.go"), strings.Index(aTest, "greeting"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Doc != "greeting returns a greeting.\n" {
+		t.Errorf("want doc of greeting, got %q", d.Doc)
+	}
+}
+
+func TestAdHocUnsavedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "b.go"), "package main\n\n// greeting returns a greeting.\nfunc greeting() string { return \"hi\" }\n")
+	a := "package main\n\nfunc main() {\n\tprintln(greeting(), farewell())\n}\n"
+	overlay := map[string][]byte{
+		filepath.Join(dir, "a.go"): []byte(a),
+		filepath.Join(dir, "c.go"): []byte("package main\n\n// farewell says goodbye.\nfunc farewell() string { return \"bye\" }\n"),
+	}
+	for _, test := range []struct {
+		at, doc string
+	}{
+		{"greeting", "greeting returns a greeting.\n"},
+		{"farewell", "farewell says goodbye.\n"},
+	} {
+		d, err := Run(filepath.Join(dir, "a.go"), strings.Index(a, test.at), overlay)
+		if err != nil {
+			t.Errorf("%s: %v", test.at, err)
+			continue
+		}
+		if d.Doc != test.doc {
+			t.Errorf("%s: want doc %q, got %q", test.at, test.doc, d.Doc)
+		}
+	}
+}
+
+func TestAdHocLocalModules(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.work":        "go 1.22\n\nuse (\n\t./m\n\t./other\n)\n",
+		"m/go.mod":       "module example.com/m\n",
+		"m/lib/lib.go":   "package lib\n\n// L is in the main module.\nconst L = 1\n",
+		"other/go.mod":   "module example.com/other\n",
+		"other/other.go": "package other\n\n// O is in the workspace.\nconst O = 2\n",
+	} {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), src)
+	}
+	t.Setenv("GOWORK", "")
+	os.Unsetenv("GOWORK")
+
+	// a new package made only of unsaved files
+	filename := filepath.Join(dir, "m", "newpkg", "c.go")
+	c := "package newpkg\n\nimport (\n\t\"example.com/m/lib\"\n\t\"example.com/other\"\n)\n\nvar _ = lib.L + other.O\n"
+	overlay := map[string][]byte{filename: []byte(c)}
+	bc := defaultBuildContext()
+	for _, test := range []struct {
+		at, doc string
+	}{
+		{"L +", "L is in the main module.\n"},
+		{"O\n", "O is in the workspace.\n"},
+	} {
+		offset := strings.Index(c, test.at)
+		pkg, nodes, err := adHocLoad(&bc, filename, offset, offset, overlay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := DocFromNodes(pkg, nodes)
+		if err != nil {
+			t.Errorf("%s: %v", test.at, err)
+			continue
+		}
+		if !strings.HasPrefix(d.Doc, test.doc) {
+			t.Errorf("%s: want doc %q, got %q", test.at, test.doc, d.Doc)
+		}
+	}
+}
+
+func TestCachedModuleDir(t *testing.T) {
+	cache := t.TempDir()
+	for _, v := range []string{"v1.0.0", "v1.2.0", "v1.10.0-rc.1"} {
+		writeTestFile(t, filepath.Join(cache, "example.com", "lib@"+v, "lib.go"), "package lib\n")
+	}
+	for _, v := range []string{"v0.1.0-rc.9", "v0.1.0-rc.10"} {
+		writeTestFile(t, filepath.Join(cache, "example.com", "pre@"+v, "pre.go"), "package pre\n")
+	}
+	t.Setenv("GOMODCACHE", cache)
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "main", "go.mod"), "module example.com/main\n\nrequire example.com/lib v1.0.0\n")
+
+	for _, test := range []struct {
+		path, srcDir, want string
+	}{
+		{"example.com/lib", filepath.Join(dir, "main"), "lib@v1.0.0"},
+		{"example.com/lib", dir, "lib@v1.2.0"},
+		// numeric prerelease identifiers compare numerically
+		{"example.com/pre", dir, "pre@v0.1.0-rc.10"},
+		{"example.com/missing", dir, ""},
+	} {
+		got := cachedModuleDir(test.path, test.srcDir)
+		if test.want == "" && got != "" || test.want != "" && got != filepath.Join(cache, "example.com", test.want) {
+			t.Errorf("%s from %s: want %s, got %s", test.path, test.srcDir, test.want, got)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	for _, test := range []struct {
+		versions []string
+		want     string
+	}{
+		{[]string{"v1.2.3", "v1.10.0", "v1.9.9"}, "v1.10.0"},
+		{[]string{"v2.0.0+incompatible", "v1.99.99"}, "v2.0.0+incompatible"},
+		{[]string{"v1.0.0", "v1.1.0-pre"}, "v1.0.0"},
+		{[]string{"v1.0.0-alpha", "v1.0.0-beta"}, "v1.0.0-beta"},
+		{[]string{"v1.0.0-rc.9", "v1.0.0-rc.10"}, "v1.0.0-rc.10"},
+		{[]string{"latest", "1.0.0"}, ""},
+		{nil, ""},
+	} {
+		if got := latestVersion(test.versions); got != test.want {
+			t.Errorf("latestVersion(%q) = %q, want %q", test.versions, got, test.want)
+		}
+	}
+}
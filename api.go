@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// apiSince reports the Go release in which the standard library symbol sym
+// of package pkgPath was introduced, such as "go1.21".  It consults the
+// api/go1.*.txt files of the local GOROOT, and returns the empty string if
+// the symbol is not found there or dates from Go 1.0.
+//
+// Symbols are named as in the api files: "Name" for package-level
+// declarations, and "Type.Name" for methods and struct fields.
+func apiSince(pkgPath, sym string) string {
+	apiOnce.Do(loadAPI)
+	if v := apiVersions[pkgPath+" "+sym]; v != "go1" {
+		return v
+	}
+	return ""
+}
+
+var (
+	apiOnce sync.Once
+
+	// apiVersions maps "pkgPath sym" to the first release listing it
+	apiVersions map[string]string
+)
+
+// loadAPI reads the api files of the GOROOT into apiVersions, from the
+// oldest release to the newest.
+func loadAPI() {
+	apiVersions = make(map[string]string)
+	files, _ := filepath.Glob(filepath.Join(build.Default.GOROOT, "api", "go1*.txt"))
+	sort.Slice(files, func(i, j int) bool {
+		return compareGoVersions(apiFileVersion(files[i]), apiFileVersion(files[j])) < 0
+	})
+	for _, file := range files {
+		readAPIFile(file, apiFileVersion(file))
+	}
+}
+
+// apiFileVersion returns the Go version of an api file, e.g. "go1.21" for go1.21.txt.
+func apiFileVersion(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), ".txt")
+}
+
+// readAPIFile records version in apiVersions for the symbols of file
+// that no older file listed.
+func readAPIFile(file, version string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "pkg ") || strings.Contains(line, "//deprecated") {
+			continue
+		}
+		pkgPath, rest := splitWord(line[len("pkg "):])
+		pkgPath = strings.TrimSuffix(pkgPath, ",")
+		if strings.HasPrefix(rest, "(") {
+			// platform-specific entry, e.g. "pkg syscall (linux-386), ..."
+			i := strings.Index(rest, "), ")
+			if i < 0 {
+				continue
+			}
+			rest = rest[i+3:]
+		}
+		sym := apiSymbol(rest)
+		if sym == "" {
+			continue
+		}
+		key := pkgPath + " " + sym
+		if _, ok := apiVersions[key]; !ok {
+			apiVersions[key] = version
+		}
+	}
+}
+
+// apiSymbol extracts the symbol name from the declaration part of an api
+// file line, such as "method (*Buffer) Len() int" or "type Request struct, URL *url.URL".
+func apiSymbol(decl string) string {
+	kind, rest := splitWord(decl)
+	switch kind {
+	case "func", "const", "var":
+		return apiIdent(rest)
+	case "method":
+		// (*T) Name(...) or (T[$0]) Name(...)
+		i := strings.Index(rest, ") ")
+		if i < 0 {
+			return ""
+		}
+		recv := strings.TrimLeft(rest[1:i], "*")
+		return apiIdent(recv) + "." + apiIdent(rest[i+2:])
+	case "type":
+		name := apiIdent(rest)
+		// struct fields and interface methods: "T struct, Field ..."
+		if i := topLevelComma(rest); i >= 0 {
+			return name + "." + apiIdent(strings.TrimSpace(rest[i+1:]))
+		}
+		return name
+	}
+	return ""
+}
+
+// topLevelComma returns the index of the first comma in s that is not
+// nested in brackets, braces or parentheses, or -1.
+func topLevelComma(s string) int {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitWord(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// apiIdent returns the leading Go identifier in s.
+func apiIdent(s string) string {
+	for i, r := range s {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// apiSymbolName returns the name of obj as it appears in the api files.
+// For struct fields, the name of the enclosing struct type must be provided.
+func apiSymbolName(obj types.Object, structName string) string {
+	switch obj := obj.(type) {
+	case *types.Func:
+		sig, ok := obj.Type().(*types.Signature)
+		if !ok || sig.Recv() == nil {
+			return obj.Name()
+		}
+		recv := sig.Recv().Type()
+		if p, ok := recv.(*types.Pointer); ok {
+			recv = p.Elem()
+		}
+		if named, ok := recv.(*types.Named); ok {
+			return named.Obj().Name() + "." + obj.Name()
+		}
+		return ""
+	case *types.Var:
+		if obj.IsField() {
+			if structName == "" {
+				return ""
+			}
+			return structName + "." + obj.Name()
+		}
+	}
+	return obj.Name()
+}
+
+// isStdlibFile reports whether filename belongs to the standard library
+// of the GOROOT in use.
+func isStdlibFile(filename string) bool {
+	src := filepath.Join(build.Default.GOROOT, "src") + string(filepath.Separator)
+	return strings.HasPrefix(filename, src)
+}
+
+// goVersionWarning returns a warning if the go.mod file governing filename
+// declares an older Go version than since, which is the version that
+// introduced the documented symbol name.
+func goVersionWarning(filename, name, since string) string {
+	if since == "" {
+		return ""
+	}
+	gomod := findGoMod(filepath.Dir(filename))
+	if gomod == "" {
+		return ""
+	}
+	mod, err := parseGoMod(gomod)
+	if err != nil || mod.Go == "" {
+		return ""
+	}
+	if compareGoVersions("go"+mod.Go, since) < 0 {
+		return fmt.Sprintf("%s requires %s, but %s declares go %s", name, since, gomod, mod.Go)
+	}
+	return ""
+}
+
+// compareGoVersions compares the major and minor components of two Go
+// versions of the form "go1.21" or "go1.21.3", returning -1, 0 or +1.
+func compareGoVersions(a, b string) int {
+	va, vb := goVersionParts(a), goVersionParts(b)
+	for i := range va {
+		if va[i] < vb[i] {
+			return -1
+		}
+		if va[i] > vb[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+func goVersionParts(v string) [2]int {
+	var parts [2]int
+	fields := strings.SplitN(strings.TrimPrefix(v, "go"), ".", 3)
+	for i := 0; i < len(parts) && i < len(fields); i++ {
+		// drop suffixes such as "rc1"
+		n := strings.IndexFunc(fields[i], func(r rune) bool { return r < '0' || r > '9' })
+		if n >= 0 {
+			fields[i] = fields[i][:n]
+		}
+		parts[i], _ = strconv.Atoi(fields[i])
+	}
+	return parts
+}
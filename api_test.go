@@ -0,0 +1,83 @@
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPISymbol(t *testing.T) {
+	for _, test := range []struct {
+		decl, want string
+	}{
+		{"func Appendf([]uint8, string, ...interface{}) []uint8", "Appendf"},
+		{"method (*Buffer) AvailableBuffer() []uint8", "Buffer.AvailableBuffer"},
+		{"method (Seq[$0]) Foo()", "Seq.Foo"},
+		{"type Request struct, Pattern string", "Request.Pattern"},
+		{"type Locker interface, Lock()", "Locker.Lock"},
+		{"type Locker interface { Lock, Unlock }", "Locker"},
+		{"type Seq2[$0 interface{}, $1 interface{}] func(func($0, $1) bool)", "Seq2"},
+		{"const LOG_ALERT Priority", "LOG_ALERT"},
+		{"var ErrShortWrite error", "ErrShortWrite"},
+	} {
+		if got := apiSymbol(test.decl); got != test.want {
+			t.Errorf("apiSymbol(%q): want %q, got %q", test.decl, test.want, got)
+		}
+	}
+}
+
+func TestAPISince(t *testing.T) {
+	if _, err := os.Stat(filepath.Join(build.Default.GOROOT, "api", "go1.txt")); err != nil {
+		t.Skip("GOROOT has no api files")
+	}
+	for _, test := range []struct {
+		pkg, sym, want string
+	}{
+		{"fmt", "Println", ""},
+		{"fmt", "Appendf", "go1.19"},
+		{"strings", "Cut", "go1.18"},
+		{"net/http", "Request.Pattern", "go1.23"},
+		{"sync", "Map.CompareAndSwap", "go1.20"},
+		{"fmt", "NoSuchThing", ""},
+	} {
+		if got := apiSince(test.pkg, test.sym); got != test.want {
+			t.Errorf("apiSince(%q, %q): want %q, got %q", test.pkg, test.sym, test.want, got)
+		}
+	}
+}
+
+func TestCompareGoVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"go1", "go1.1", -1},
+		{"go1.9", "go1.10", -1},
+		{"go1.21.3", "go1.21", 0},
+		{"go1.22rc1", "go1.21", 1},
+	} {
+		if got := compareGoVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareGoVersions(%q, %q): want %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+}
+
+func TestGoVersionWarning(t *testing.T) {
+	dir := t.TempDir()
+
+	gomod := "module example.com/old // comment\n\ngo 1.18\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "main.go")
+
+	if w := goVersionWarning(filename, "fmt.Appendf", "go1.19"); !strings.Contains(w, "fmt.Appendf requires go1.19") {
+		t.Errorf("expected warning about go1.19, got %q", w)
+	}
+	if w := goVersionWarning(filename, "strings.Cut", "go1.18"); w != "" {
+		t.Errorf("expected no warning, got %q", w)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"go/doc/comment"
+)
+
+// Block is one structural element of a doc comment, such as a paragraph,
+// a heading, a code block, a list or a set of link definitions.
+// It allows clients to render documentation natively without having
+// to reimplement Go's doc comment grammar.
+type Block struct {
+	// Kind is one of "paragraph", "heading", "code", "list" or "links".
+	Kind string `json:"kind"`
+
+	// Text holds the inline content of paragraphs and headings.
+	Text []Span `json:"text,omitempty"`
+
+	// Code holds the preformatted text of a code block.
+	Code string `json:"code,omitempty"`
+
+	// Items holds the items of a list.
+	Items []Item `json:"items,omitempty"`
+
+	// Links holds the link definitions of the comment.
+	Links []LinkDef `json:"links,omitempty"`
+}
+
+// Item is a single item in a bullet or numbered list.
+type Item struct {
+	// Number is the decimal number of the item in a numbered list,
+	// or empty in a bullet list.
+	Number  string  `json:"number,omitempty"`
+	Content []Block `json:"content"`
+}
+
+// Span is a run of inline text within a paragraph, heading or list item.
+type Span struct {
+	// Kind is one of "text", "italic", "link" (a URL) or "doclink"
+	// (a reference to a Go package or symbol).
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+	URL  string `json:"url,omitempty"`
+
+	// Import, Recv and Name identify the target of a doclink.
+	Import string `json:"import,omitempty"`
+	Recv   string `json:"recv,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// LinkDef is a link definition of the form "[Text]: URL".
+type LinkDef struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+const docLinkBaseURL = "https://pkg.go.dev"
+
+// docBlocks parses a doc comment into a sequence of blocks.
+func docBlocks(text string) []Block {
+	d := parseComment(text)
+	blocks := convertBlocks(d.Content)
+	if len(d.Links) > 0 {
+		links := Block{Kind: "links"}
+		for _, def := range d.Links {
+			links.Links = append(links.Links, LinkDef{Text: def.Text, URL: def.URL})
+		}
+		blocks = append(blocks, links)
+	}
+	return blocks
+}
+
+func convertBlocks(content []comment.Block) []Block {
+	blocks := make([]Block, 0, len(content))
+	for _, b := range content {
+		switch b := b.(type) {
+		case *comment.Paragraph:
+			blocks = append(blocks, Block{Kind: "paragraph", Text: convertText(b.Text)})
+		case *comment.Heading:
+			blocks = append(blocks, Block{Kind: "heading", Text: convertText(b.Text)})
+		case *comment.Code:
+			blocks = append(blocks, Block{Kind: "code", Code: b.Text})
+		case *comment.List:
+			list := Block{Kind: "list"}
+			for _, item := range b.Items {
+				list.Items = append(list.Items, Item{
+					Number:  item.Number,
+					Content: convertBlocks(item.Content),
+				})
+			}
+			blocks = append(blocks, list)
+		}
+	}
+	return blocks
+}
+
+func convertText(text []comment.Text) []Span {
+	spans := make([]Span, 0, len(text))
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			spans = append(spans, Span{Kind: "text", Text: string(t)})
+		case comment.Italic:
+			spans = append(spans, Span{Kind: "italic", Text: string(t)})
+		case *comment.Link:
+			spans = append(spans, Span{Kind: "link", Text: plainText(t.Text), URL: t.URL})
+		case *comment.DocLink:
+			spans = append(spans, Span{
+				Kind:   "doclink",
+				Text:   plainText(t.Text),
+				URL:    t.DefaultURL(docLinkBaseURL),
+				Import: t.ImportPath,
+				Recv:   t.Recv,
+				Name:   t.Name,
+			})
+		}
+	}
+	return spans
+}
+
+// plainText flattens the text of a link.
+func plainText(text []comment.Text) string {
+	var s string
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			s += string(t)
+		case comment.Italic:
+			s += string(t)
+		case *comment.Link:
+			s += plainText(t.Text)
+		case *comment.DocLink:
+			s += plainText(t.Text)
+		}
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocBlocks(t *testing.T) {
+	text := `Package foo does things. See [io.Reader] and [Go spec].
+
+# Usage
+
+Call it like so:
+
+	foo.Do()
+
+The options are:
+  - fast, see https://example.com
+  - slow
+
+Steps:
+ 1. Open.
+
+[Go spec]: https://go.dev/ref/spec
+`
+	want := []Block{
+		{Kind: "paragraph", Text: []Span{
+			{Kind: "text", Text: "Package foo does things. See "},
+			{Kind: "doclink", Text: "io.Reader", URL: "https://pkg.go.dev/io#Reader", Import: "io", Name: "Reader"},
+			{Kind: "text", Text: " and "},
+			{Kind: "link", Text: "Go spec", URL: "https://go.dev/ref/spec"},
+			{Kind: "text", Text: "."},
+		}},
+		{Kind: "heading", Text: []Span{{Kind: "text", Text: "Usage"}}},
+		{Kind: "paragraph", Text: []Span{{Kind: "text", Text: "Call it like so:"}}},
+		{Kind: "code", Code: "foo.Do()\n"},
+		{Kind: "paragraph", Text: []Span{{Kind: "text", Text: "The options are:"}}},
+		{Kind: "list", Items: []Item{
+			{Content: []Block{{Kind: "paragraph", Text: []Span{
+				{Kind: "text", Text: "fast, see "},
+				{Kind: "link", Text: "https://example.com", URL: "https://example.com"},
+			}}}},
+			{Content: []Block{{Kind: "paragraph", Text: []Span{{Kind: "text", Text: "slow"}}}}},
+		}},
+		{Kind: "paragraph", Text: []Span{{Kind: "text", Text: "Steps:"}}},
+		{Kind: "list", Items: []Item{
+			{Number: "1", Content: []Block{{Kind: "paragraph", Text: []Span{{Kind: "text", Text: "Open."}}}}},
+		}},
+		{Kind: "links", Links: []LinkDef{{Text: "Go spec", URL: "https://go.dev/ref/spec"}}},
+	}
+
+	got := docBlocks(text)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, got)
+	}
+}
+
+func TestDocBlocksEmpty(t *testing.T) {
+	if got := docBlocks(""); len(got) != 0 {
+		t.Errorf("expected no blocks for empty doc, got %+v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// sourceNode returns the node whose source should be shown for the
+// declaration found at the start of path: the whole function for
+// functions and methods, the type spec for types, and the full
+// declaration block for constants and variables.
+// It returns nil if there is no such declaration (e.g. for struct
+// fields, parameters and local variables).
+func sourceNode(path []ast.Node) ast.Node {
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.Ident:
+			continue
+		case *ast.FuncDecl:
+			return n
+		case *ast.TypeSpec:
+			if i+1 < len(path) {
+				if gen, ok := path[i+1].(*ast.GenDecl); ok && !gen.Lparen.IsValid() {
+					return gen
+				}
+			}
+			return n
+		case *ast.ValueSpec:
+			if i+1 < len(path) {
+				if gen, ok := path[i+1].(*ast.GenDecl); ok {
+					return gen
+				}
+			}
+			return nil
+		case *ast.GenDecl:
+			return n
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// declSource returns the source code of the declaration node, read from
+// the overlay if the file has been modified, or from disk.
+// At most maxLines lines are returned (no limit if maxLines <= 0).
+func declSource(fset *token.FileSet, node ast.Node, overlay map[string][]byte, maxLines int) (string, error) {
+	if node == nil || !node.Pos().IsValid() {
+		return "", nil
+	}
+	filename := fset.Position(node.Pos()).Filename
+	src, err := readSource(filename, overlay)
+	if err != nil {
+		return "", err
+	}
+
+	start := fset.Position(node.Pos()).Offset
+	end := fset.Position(node.End()).Offset
+	if fn, ok := node.(*ast.FuncDecl); ok {
+		// function bodies are dropped before type checking,
+		// so find the end of the function in a fresh parse
+		if end = funcEnd(filename, src, start); end < 0 {
+			return "", fmt.Errorf("cannot find function %s in %s", fn.Name.Name, filename)
+		}
+	}
+	if start > end || end > len(src) {
+		return "", fmt.Errorf("%s has changed since it was loaded", filename)
+	}
+	end = trailingComment(src, end)
+
+	text := string(src[start:end])
+	if _, ok := node.(*ast.TypeSpec); ok {
+		// the spec is part of a type ( ... ) block, dedent it
+		text = "type " + dedent(text, lineIndent(src, start))
+	}
+	return truncateLines(text, maxLines), nil
+}
+
+// funcEnd returns the offset of the end of the function declaration
+// starting at offset start, or -1 if there isn't one.
+func funcEnd(filename string, src []byte, start int) int {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if file == nil {
+		return -1
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fset.Position(fn.Pos()).Offset == start {
+			return fset.Position(fn.End()).Offset
+		}
+	}
+	return -1
+}
+
+// trailingComment extends end to include a line comment that follows it
+// on the same line.
+func trailingComment(src []byte, end int) int {
+	eol := bytes.IndexByte(src[end:], '\n')
+	if eol < 0 {
+		eol = len(src) - end
+	}
+	if rest := bytes.TrimSpace(src[end : end+eol]); bytes.HasPrefix(rest, []byte("//")) {
+		return end + eol
+	}
+	return end
+}
+
+// lineIndent returns the whitespace at the start of the line containing offset.
+func lineIndent(src []byte, offset int) string {
+	bol := bytes.LastIndexByte(src[:offset], '\n') + 1
+	i := bol
+	for i < offset && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	return string(src[bol:i])
+}
+
+// dedent removes prefix from all but the first line of text.
+func dedent(text, prefix string) string {
+	if prefix == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], prefix)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateLines limits text to max lines, noting how many were dropped.
+func truncateLines(text string, max int) string {
+	lines := strings.Split(text, "\n")
+	if max <= 0 || len(lines) <= max {
+		return text
+	}
+	return strings.Join(lines[:max], "\n") + fmt.Sprintf("\n// ... (%d more lines)", len(lines)-max)
+}
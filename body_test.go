@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestTruncateLines(t *testing.T) {
+	text := "func F() {\n\ta()\n\tb()\n\tc()\n}"
+	if got := truncateLines(text, 0); got != text {
+		t.Errorf("no limit: got %q", got)
+	}
+	if got := truncateLines(text, 5); got != text {
+		t.Errorf("exact limit: got %q", got)
+	}
+	want := "func F() {\n\ta()\n// ... (3 more lines)"
+	if got := truncateLines(text, 2); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestTrailingComment(t *testing.T) {
+	src := []byte("const A = 1 // A is one\nconst B = 2\nconst C = 3; var D = 4\n")
+	if got := trailingComment(src, 11); got != 23 {
+		t.Errorf("expected comment to be included, got end %d", got)
+	}
+	if got := trailingComment(src, 35); got != 35 {
+		t.Errorf("expected end to be unchanged at end of line, got %d", got)
+	}
+	if got := trailingComment(src, 47); got != 47 {
+		t.Errorf("expected end to be unchanged before code, got %d", got)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/build/constraint"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// BuildContext is the target platform and build tags that packages are
+// loaded for, and the other settings the go command loads them with.
+type BuildContext struct {
+	GOOS   string   `json:"goos"`
+	GOARCH string   `json:"goarch"`
+	Tags   []string `json:"tags,omitempty"`
+	Flags  []string `json:"flags,omitempty"` // from -buildflags
+	Env    []string `json:"env,omitempty"`   // from -env
+	Dir    string   `json:"dir,omitempty"`   // from -dir
+}
+
+func (bc *BuildContext) String() string {
+	s := "GOOS=" + bc.GOOS + " GOARCH=" + bc.GOARCH
+	if len(bc.Tags) > 0 {
+		s += " -tags=" + strings.Join(bc.Tags, ",")
+	}
+	if len(bc.Flags) > 0 {
+		s += " " + strings.Join(bc.Flags, " ")
+	}
+	if len(bc.Env) > 0 {
+		s += " (with " + strings.Join(bc.Env, " ") + ")"
+	}
+	if bc.Dir != "" {
+		s += " in " + bc.Dir
+	}
+	return s
+}
+
+// envFlag is a flag.Value for the repeatable -env flag.
+type envFlag []string
+
+func (f *envFlag) String() string { return strings.Join(*f, " ") }
+
+func (f *envFlag) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("%q is not of the form NAME=value", s)
+	}
+	*f = append(*f, s)
+	return nil
+}
+
+// defaultBuildContext returns the build context given by the -goos,
+// -goarch, -tags, -buildflags, -env and -dir flags, falling back to the
+// environment.  GOOS and GOARCH can also be set with -env.
+func defaultBuildContext() BuildContext {
+	bc := BuildContext{
+		GOOS:   build.Default.GOOS,
+		GOARCH: build.Default.GOARCH,
+		Tags:   build.Default.BuildTags,
+		Flags:  strings.Fields(*buildFlags),
+		Env:    extraEnv,
+	}
+	if v, ok := bc.getenv("GOOS"); ok {
+		bc.GOOS = v
+	}
+	if v, ok := bc.getenv("GOARCH"); ok {
+		bc.GOARCH = v
+	}
+	if *goos != "" {
+		bc.GOOS = *goos
+	}
+	if *goarch != "" {
+		bc.GOARCH = *goarch
+	}
+	if *workDir != "" {
+		bc.Dir, _ = filepath.Abs(*workDir)
+	}
+	return bc
+}
+
+// getenv returns the value of the variable name set by -env, if any.
+func (bc *BuildContext) getenv(name string) (string, bool) {
+	for i := len(bc.Env) - 1; i >= 0; i-- {
+		if v := strings.TrimPrefix(bc.Env[i], name+"="); v != bc.Env[i] {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// isDefault reports whether bc is the build context of the environment.
+func (bc *BuildContext) isDefault() bool {
+	return bc.GOOS == build.Default.GOOS && bc.GOARCH == build.Default.GOARCH && len(bc.Tags) == 0 &&
+		len(bc.Flags) == 0 && len(bc.Env) == 0 && bc.Dir == ""
+}
+
+// env returns the environment for running the go command in bc.
+func (bc *BuildContext) env() []string {
+	env := append(os.Environ(), bc.Env...)
+	// GOOS and GOARCH come last, as -goos and -goarch override -env
+	return append(env, "GOOS="+bc.GOOS, "GOARCH="+bc.GOARCH)
+}
+
+// config returns the configuration for loading packages in mode from dir,
+// or from -dir if it is given, in bc.
+func (bc *BuildContext) config(dir string, mode packages.LoadMode) *packages.Config {
+	cfg := &packages.Config{
+		Dir:        dir,
+		Env:        append(bc.env(), workspaceEnv(dir)...),
+		BuildFlags: bc.buildFlags(),
+		Mode:       mode,
+	}
+	if bc.Dir != "" {
+		cfg.Dir = bc.Dir
+	}
+	return cfg
+}
+
+// buildFlags returns the flags for the go command that select bc's tags,
+// followed by those given by -buildflags.
+func (bc *BuildContext) buildFlags() []string {
+	var flags []string
+	if len(bc.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(bc.Tags, ","))
+	}
+	return append(flags, bc.Flags...)
+}
+
+// context returns the go/build context for bc, reading filename's
+// contents from src.
+func (bc *BuildContext) context(filename string, src []byte) build.Context {
+	ctxt := build.Default
+	ctxt.GOOS = bc.GOOS
+	ctxt.GOARCH = bc.GOARCH
+	ctxt.BuildTags = bc.Tags
+	if v, ok := bc.getenv("CGO_ENABLED"); ok {
+		ctxt.CgoEnabled = v == "1"
+	}
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		if path == filename {
+			return ioutil.NopCloser(bytes.NewReader(src)), nil
+		}
+		return os.Open(path)
+	}
+	return ctxt
+}
+
+// matches reports whether the file with the given contents is built in bc.
+func (bc *BuildContext) matches(filename string, src []byte) bool {
+	ctxt := bc.context(filename, src)
+	match, err := ctxt.MatchFile(filepath.Dir(filename), filepath.Base(filename))
+	return err == nil && match
+}
+
+// forFile returns a build context in which the file with the given
+// contents is built, changing as little of bc as it can, or nil if the
+// file is already built in bc or no such context is found.
+func (bc *BuildContext) forFile(filename string, src []byte) *BuildContext {
+	if bc.matches(filename, src) {
+		return nil
+	}
+	nameOS, nameArch := fileNameOSArch(filename)
+	expr, _ := constraint.Parse(buildConstraint(src))
+
+	// candidates, starting with the current values
+	osList, archList := []string{bc.GOOS}, []string{bc.GOARCH}
+	var tags []string
+	if nameOS != "" {
+		osList = []string{nameOS}
+	}
+	if nameArch != "" {
+		archList = []string{nameArch}
+	}
+	for _, tag := range constraintTags(expr) {
+		switch {
+		case knownOS[tag]:
+			if nameOS == "" {
+				osList = append(osList, tag)
+			}
+		case knownArch[tag]:
+			if nameArch == "" {
+				archList = append(archList, tag)
+			}
+		case tag == "unix" || tag == "cgo" || tag == "gc" || tag == "gccgo" || strings.HasPrefix(tag, "go1."):
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 8 {
+		// too many subsets to try
+		tags = tags[:8]
+	}
+
+	for _, targetOS := range osList {
+		for _, targetArch := range archList {
+			for _, subset := range tagSubsets(tags) {
+				alt := *bc
+				alt.GOOS, alt.GOARCH = targetOS, targetArch
+				alt.Tags = append(append([]string(nil), bc.Tags...), subset...)
+				if alt.matches(filename, src) {
+					return &alt
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fileNameOSArch returns the GOOS and GOARCH that the name of a Go file
+// restricts it to, as in foo_windows.go or foo_linux_arm64_test.go.
+func fileNameOSArch(filename string) (goOS, goArch string) {
+	name := strings.TrimSuffix(filepath.Base(filename), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	l := strings.Split(name, "_")
+	if n := len(l); n >= 3 && knownOS[l[n-2]] && knownArch[l[n-1]] {
+		return l[n-2], l[n-1]
+	} else if n >= 2 && knownOS[l[n-1]] {
+		return l[n-1], ""
+	} else if n >= 2 && knownArch[l[n-1]] {
+		return "", l[n-1]
+	}
+	return "", ""
+}
+
+// constraintTags returns the tags used in the build constraint expr.
+func constraintTags(expr constraint.Expr) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	var walk func(constraint.Expr)
+	walk = func(x constraint.Expr) {
+		switch x := x.(type) {
+		case *constraint.TagExpr:
+			if !seen[x.Tag] {
+				seen[x.Tag] = true
+				tags = append(tags, x.Tag)
+			}
+		case *constraint.NotExpr:
+			walk(x.X)
+		case *constraint.AndExpr:
+			walk(x.X)
+			walk(x.Y)
+		case *constraint.OrExpr:
+			walk(x.X)
+			walk(x.Y)
+		}
+	}
+	if expr != nil {
+		walk(expr)
+	}
+	return tags
+}
+
+// tagSubsets returns the subsets of tags, smallest first.
+func tagSubsets(tags []string) [][]string {
+	subsets := [][]string{nil}
+	for size := 1; size <= len(tags); size++ {
+		for mask := 0; mask < 1<<uint(len(tags)); mask++ {
+			var subset []string
+			for i, tag := range tags {
+				if mask&(1<<uint(i)) != 0 {
+					subset = append(subset, tag)
+				}
+			}
+			if len(subset) == size {
+				subsets = append(subsets, subset)
+			}
+		}
+	}
+	return subsets
+}
+
+// The operating systems and architectures known to go/build.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+		"js": true, "linux": true, "nacl": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true,
+		"armbe": true, "arm64": true, "arm64be": true, "loong64": true,
+		"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true,
+		"s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
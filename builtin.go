@@ -12,7 +12,8 @@ import (
 )
 
 func builtinPackage() *doc.Package {
-	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadFiles}, "builtin")
+	bc := defaultBuildContext()
+	pkgs, err := packages.Load(bc.config("", packages.LoadFiles), "builtin")
 	if err != nil {
 		log.Fatalf("error getting metadata of builtin: %v", err)
 	}
@@ -58,7 +59,7 @@ func findInBuiltin(name string, obj types.Object, prog *packages.Package) (docst
 	// funcs
 	for _, f := range funcs {
 		if f.Name == name {
-			return f.Doc, formatNode(f.Decl, obj, prog)
+			return f.Doc, formatNode(f.Decl, obj, prog.Fset)
 		}
 	}
 
@@ -82,7 +83,7 @@ func findInBuiltin(name string, obj types.Object, prog *packages.Package) (docst
 	// types
 	for _, t := range pkg.Types {
 		if t.Name == name {
-			return t.Doc, formatNode(t.Decl, obj, prog)
+			return t.Doc, formatNode(t.Decl, obj, prog.Fset)
 		}
 	}
 
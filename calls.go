@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Call is a function in a call hierarchy.
+type Call struct {
+	Name     string  `json:"name"`               // e.g. "pkg.Func" or "(*pkg.T).Method"
+	Pos      string  `json:"pos"`                // position of the function's declaration
+	Site     string  `json:"site"`               // position of the (first) call
+	Synopsis string  `json:"synopsis,omitempty"` // first sentence of the function's doc
+	Calls    []*Call `json:"calls,omitempty"`    // the next level of the hierarchy, if -depth > 1
+}
+
+// callEdge records that caller refers to callee (by calling it or by
+// using it as a method value or method expression) at site.
+type callEdge struct {
+	caller, callee string // object keys, see objKey
+	site           token.Position
+}
+
+// callGraph is a static call graph of the functions of a module.
+type callGraph struct {
+	edges []callEdge
+	funcs map[string]*callFunc
+}
+
+type callFunc struct {
+	obj  *types.Func
+	fset *token.FileSet
+	decl *ast.FuncDecl // nil if declared outside the module
+}
+
+// buildCallGraph builds the call graph of the loaded packages.
+func buildCallGraph(pkgs []*packages.Package) *callGraph {
+	g := &callGraph{funcs: make(map[string]*callFunc)}
+	seen := make(map[callEdge]bool)
+	for _, p := range pkgs {
+		if p.TypesInfo == nil {
+			continue
+		}
+		for _, f := range p.Syntax {
+			for _, decl := range f.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				caller, ok := p.TypesInfo.Defs[fn.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				callerKey := g.add(p.Fset, caller, fn)
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					id, ok := n.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					callee, ok := p.TypesInfo.Uses[id].(*types.Func)
+					if !ok {
+						return true
+					}
+					e := callEdge{callerKey, g.add(p.Fset, callee, nil), p.Fset.Position(id.Pos())}
+					if !seen[e] {
+						seen[e] = true
+						g.edges = append(g.edges, e)
+					}
+					return true
+				})
+			}
+		}
+	}
+	return g
+}
+
+// add records fn in the graph and returns its key.
+func (g *callGraph) add(fset *token.FileSet, fn *types.Func, decl *ast.FuncDecl) string {
+	key := objKey(fset, fn)
+	if f := g.funcs[key]; f == nil || f.decl == nil {
+		g.funcs[key] = &callFunc{obj: fn, fset: fset, decl: decl}
+	}
+	return key
+}
+
+// callers returns the edges to the function key, including calls through
+// interface methods that the function's receiver type implements.
+func (g *callGraph) callers(key string) []callEdge {
+	var result []callEdge
+	for _, e := range g.edges {
+		if e.callee == key || g.implements(key, e.callee) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// callees returns the edges from the function key.
+func (g *callGraph) callees(key string) []callEdge {
+	var result []callEdge
+	for _, e := range g.edges {
+		if e.caller == key {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// implements reports whether the concrete method key implements the
+// interface method ifaceKey, i.e. a call to ifaceKey may dispatch to key.
+func (g *callGraph) implements(key, ifaceKey string) bool {
+	m, im := g.funcs[key], g.funcs[ifaceKey]
+	if m == nil || im == nil || m.obj.Name() != im.obj.Name() {
+		return false
+	}
+	recv, irecv := recvType(m.obj), recvType(im.obj)
+	if recv == nil || irecv == nil {
+		return false
+	}
+	iface, ok := irecv.Underlying().(*types.Interface)
+	if !ok || types.IsInterface(recv) {
+		return false
+	}
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	return types.Implements(types.NewPointer(recv), iface)
+}
+
+func recvType(fn *types.Func) types.Type {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+	return sig.Recv().Type()
+}
+
+// hierarchy returns the callers (or callees) of the function key,
+// to the given depth.  The result is nil only if depth is exhausted or
+// key is already on the path being explored.
+func (g *callGraph) hierarchy(key string, callers bool, depth int, visited map[string]bool) []*Call {
+	if depth <= 0 || visited[key] {
+		return nil
+	}
+	visited[key] = true
+	defer delete(visited, key)
+
+	edges, other := g.callees(key), func(e callEdge) string { return e.callee }
+	if callers {
+		edges, other = g.callers(key), func(e callEdge) string { return e.caller }
+	}
+
+	calls := []*Call{}
+	seen := make(map[string]bool)
+	for _, e := range edges {
+		k := other(e)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		f := g.funcs[k]
+		calls = append(calls, &Call{
+			Name:     funcName(f.obj),
+			Pos:      f.fset.Position(f.obj.Pos()).String(),
+			Site:     e.site.String(),
+			Synopsis: funcSynopsis(f),
+			Calls:    g.hierarchy(k, callers, depth-1, visited),
+		})
+	}
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].Name < calls[j].Name })
+	return calls
+}
+
+// funcName returns the name of fn qualified by its package name
+// and receiver type, e.g. "(*bytes.Buffer).Write".
+func funcName(fn *types.Func) string {
+	qual := func(p *types.Package) string { return p.Name() }
+	if recv := recvType(fn); recv != nil {
+		return fmt.Sprintf("(%s).%s", types.TypeString(recv, qual), fn.Name())
+	}
+	if fn.Pkg() == nil {
+		return fn.Name()
+	}
+	return fn.Pkg().Name() + "." + fn.Name()
+}
+
+// funcSynopsis returns the first sentence of the function's documentation.
+// Functions declared outside of the module have no syntax tree, so their
+// declaring file is parsed.
+func funcSynopsis(f *callFunc) string {
+	if f.decl != nil {
+		return doc.Synopsis(f.decl.Doc.Text())
+	}
+	pos := f.fset.Position(f.obj.Pos())
+	_, path := declPath(pos.Filename, pos.Line, f.obj.Name())
+	return doc.Synopsis(declDoc(path))
+}
+
+// callHierarchy fills in the callers and/or callees of the function
+// documented by d, as requested by the -callers and -callees flags.
+func callHierarchy(bc *BuildContext, filename string, fset *token.FileSet, d *Doc, overlay map[string][]byte) error {
+	fn, ok := d.obj.(*types.Func)
+	if !ok {
+		return errors.New("call hierarchy requires a function or method")
+	}
+	pkgs, err := loadModule(bc, filename, overlay)
+	if err != nil {
+		return err
+	}
+	g := buildCallGraph(pkgs)
+	key := objKey(fset, fn)
+	if *showCallers {
+		d.Callers = g.hierarchy(key, true, *callDepth, make(map[string]bool))
+	}
+	if *showCallees {
+		d.Callees = g.hierarchy(key, false, *callDepth, make(map[string]bool))
+	}
+	return nil
+}
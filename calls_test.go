@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func callNames(t *testing.T, want []string, calls []*Call) {
+	t.Helper()
+	var got []string
+	for _, c := range calls {
+		got = append(got, c.Name)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %v, got %v", want, got)
+	}
+}
+
+func TestCallHierarchyDepth(t *testing.T) {
+	dir := filepath.Join(".", "testdata", "package")
+	mods := []packagestest.Module{
+		{Name: "somepkg", Files: packagestest.MustCopyFileTree(dir)},
+	}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		*showCallees, *callDepth = true, 2
+		defer func() { *showCallees, *callDepth = false, 1 }()
+
+		filename := exported.File("somepkg", "calls.go")
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Run(filename, strings.Index(string(src), "Report() {"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Name != "Report" {
+			t.Fatalf("want Report, got %s", d.Name)
+		}
+		callNames(t, []string{"(somepkg.Square).Area", "somepkg.Describe"}, d.Callees)
+		if len(d.Callees) != 2 {
+			return
+		}
+		describe := d.Callees[1]
+		if describe.Synopsis != "Describe prints the area of a shape." {
+			t.Errorf("unexpected synopsis %q", describe.Synopsis)
+		}
+		callNames(t, []string{"(somepkg.Shape).Area", "fmt.Println"}, describe.Calls)
+		if len(describe.Calls) == 2 && !strings.HasPrefix(describe.Calls[1].Synopsis, "Println formats") {
+			t.Errorf("expected synopsis of fmt.Println, got %q", describe.Calls[1].Synopsis)
+		}
+	})
+}
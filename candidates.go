@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// DocsFromNodes is like DocFromNodes, but documents everything the
+// identifier at the cursor denotes, most relevant first: the type and
+// the field of an embedded field, the implicit variable of each clause
+// of a type switch, and both the method and the value of a method value.
+func DocsFromNodes(pkg *packages.Package, nodes []ast.Node) ([]*Doc, error) {
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *ast.ImportSpec:
+			d, err := PackageDoc(pkg, ImportPath(node))
+			if err != nil {
+				return nil, err
+			}
+			return []*Doc{d}, nil
+		case *ast.Ident:
+			if pkg.TypesInfo == nil {
+				if doc := syntacticDoc(pkg, node, nodes[i+1:]); doc != nil {
+					return []*Doc{doc}, nil
+				}
+				continue
+			}
+			objs := candidates(pkg.TypesInfo, node, nodes[i+1:])
+			if len(objs) == 0 {
+				// as in DocFromNodes, resolve identifiers left
+				// unresolved by type errors from the syntax
+				if pkg.IllTyped {
+					if doc := syntacticDoc(pkg, node, nodes[i+1:]); doc != nil {
+						return []*Doc{doc}, nil
+					}
+				}
+				continue
+			}
+			var docs []*Doc
+			for _, obj := range objs {
+				d, err := ObjectDoc(obj, pkg)
+				if err != nil {
+					return nil, err
+				}
+				docs = append(docs, d)
+			}
+			if d, err := methodValueDoc(pkg, node, nodes[i+1:], docs[0]); err != nil {
+				return nil, err
+			} else if d != nil {
+				docs = append(docs, d)
+			}
+			return docs, nil
+		}
+	}
+	return nil, errors.New("gogetdoc: no documentation found")
+}
+
+// candidates returns the objects denoted by id, whose enclosing nodes
+// are path, in the order they are documented.
+func candidates(info *types.Info, id *ast.Ident, path []ast.Node) []types.Object {
+	if sw := typeSwitch(id, path); sw != nil {
+		var objs []types.Object
+		for _, stmt := range sw.Body.List {
+			if obj := info.Implicits[stmt]; obj != nil {
+				objs = append(objs, obj)
+			}
+		}
+		return objs
+	}
+	obj := info.ObjectOf(id)
+	if obj == nil {
+		return nil
+	}
+	if v, ok := obj.(*types.Var); ok && v.Anonymous() {
+		named := namedType(v.Type())
+		if named == nil {
+			return []types.Object{v}
+		}
+		if info.Uses[id] == named.Obj() {
+			// the declaration of the embedded field, which is primarily
+			// a use of the type (see IdentDoc)
+			return []types.Object{named.Obj(), v}
+		}
+		return []types.Object{v, named.Obj()}
+	}
+	return []types.Object{obj}
+}
+
+// methodValueDoc documents the function value of the method selected by
+// id if it is used as a method value or method expression, such as
+// r.Title in f := r.Title.  It returns nil otherwise.
+func methodValueDoc(pkg *packages.Package, id *ast.Ident, path []ast.Node, method *Doc) (*Doc, error) {
+	expr, kind, err := methodFunc(pkg, id, path)
+	if expr == nil || err != nil {
+		return nil, err
+	}
+	d := *method
+	d.Kind = kind
+	d.Decl = expr.Type
+	d.Expr = expr
+	return &d, nil
+}
+
+// objectKind describes the kind of obj for the Kind field of a Doc.
+func objectKind(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Var:
+		if obj.IsField() {
+			return "field"
+		}
+		return "var"
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return "method"
+		}
+		return "func"
+	case *types.TypeName:
+		return "type"
+	case *types.Const:
+		return "const"
+	case *types.PkgName:
+		return "package"
+	case *types.Builtin:
+		return "builtin"
+	case *types.Label:
+		return "label"
+	case *types.Nil:
+		return "nil"
+	}
+	return ""
+}
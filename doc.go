@@ -3,7 +3,11 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"go/doc"
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"go/types"
+	"io"
 )
 
 const (
@@ -19,17 +23,162 @@ type Doc struct {
 	Decl   string `json:"decl"`
 	Doc    string `json:"doc"`
 	Pos    string `json:"pos"`
+
+	// Kind is the kind of thing documented, such as "func", "field" or
+	// "package".  It tells apart the results of -all.
+	Kind string `json:"kind,omitempty"`
+
+	// Since is the Go release that introduced a standard library
+	// symbol, such as "go1.21".
+	Since string `json:"since,omitempty"`
+
+	// Source is the full source of the declaration, included when
+	// the -body flag is set.
+	Source string `json:"source,omitempty"`
+
+	// Refs lists the uses of the symbol in the current module,
+	// included when the -refs flag is set.  It is empty, but not nil,
+	// if there are none.
+	Refs []Ref `json:"refs,omitempty"`
+
+	// Callers and Callees hold the call hierarchy of a function,
+	// included when the -callers and -callees flags are set.  Like
+	// Refs, they are empty but not nil if there are no calls.
+	Callers []*Call `json:"callers,omitempty"`
+	Callees []*Call `json:"callees,omitempty"`
+
+	// Signature describes the call at the cursor, included when the
+	// -signature flag is set.
+	Signature *Signature `json:"signature,omitempty"`
+
+	// Members lists the fields and methods accessible on the type of
+	// a selector's operand, included when the -members flag is set.
+	Members []Member `json:"members,omitempty"`
+
+	// Expr describes the expression at the cursor, included when the
+	// -expr flag is set or a selection is documented.
+	Expr *Expr `json:"expr,omitempty"`
+
+	// Approximate is set when the package has type errors and the
+	// identifier was resolved from the syntax alone.
+	Approximate bool `json:"approximate,omitempty"`
+
+	// Build is the build context the package was loaded in, included
+	// when it differs from the environment's, such as for a file that
+	// only builds on another GOOS.
+	Build *BuildContext `json:"build,omitempty"`
+
+	// Module describes the module that provides the symbol.
+	Module *Module `json:"module,omitempty"`
+
+	// Warnings holds problems worth pointing out to the user,
+	// such as using a symbol that is newer than the module's Go version.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Blocks is the structured form of Doc, included when the
+	// -blocks flag is set.
+	Blocks []Block `json:"blocks,omitempty"`
+
+	decl ast.Node       // the declaration node, for -body
+	fset *token.FileSet // the file set of decl
+	obj  types.Object   // the documented object, if any
 }
 
 func (d *Doc) String() string {
 	buf := &bytes.Buffer{}
+	if d.Expr != nil {
+		fmt.Fprintf(buf, "%s\n\n", d.Expr)
+	}
 	if d.Import != "" {
 		fmt.Fprintf(buf, "import \"%s\"\n\n", d.Import)
 	}
 	fmt.Fprintf(buf, "%s\n\n", d.Decl)
+	if s := d.Signature; s != nil && s.Active >= 0 {
+		p := s.Params[s.Active]
+		fmt.Fprintf(buf, "Parameter %d: %s %s\n\n", s.Active+1, p.Name, p.Type)
+	}
 	if d.Doc == "" {
 		d.Doc = "Undocumented."
 	}
-	doc.ToText(buf, d.Doc, indent, preIndent, *linelength)
+	buf.Write(renderText(d.Doc, *linelength))
+	if d.Source != "" {
+		fmt.Fprintf(buf, "\n%s\n", d.Source)
+	}
+	if len(d.Members) > 0 {
+		fmt.Fprintf(buf, "\nMembers:\n")
+		for _, m := range d.Members {
+			fmt.Fprintf(buf, "%s%s\n", preIndent, m.Decl)
+			if m.Synopsis != "" {
+				fmt.Fprintf(buf, "%s  %s\n", preIndent, m.Synopsis)
+			}
+		}
+	}
+	if d.Refs != nil {
+		fmt.Fprintf(buf, "\n%s\n", refsSummary(d.Refs))
+		for _, r := range d.Refs {
+			if r.Func != "" {
+				fmt.Fprintf(buf, "%s%s (in %s)\n", preIndent, r.Pos, r.Func)
+			} else {
+				fmt.Fprintf(buf, "%s%s\n", preIndent, r.Pos)
+			}
+		}
+	}
+	if d.Callers != nil {
+		writeCalls(buf, "Callers", d.Callers)
+	}
+	if d.Callees != nil {
+		writeCalls(buf, "Callees", d.Callees)
+	}
+	if d.Since != "" {
+		fmt.Fprintf(buf, "\nAdded in %s.\n", d.Since)
+	}
+	if d.Build != nil {
+		fmt.Fprintf(buf, "\nBuild context: %s\n", d.Build)
+	}
+	for _, w := range d.Warnings {
+		fmt.Fprintf(buf, "\nWarning: %s\n", w)
+	}
 	return buf.String()
 }
+
+// writeCalls writes a call hierarchy under the given title.
+func writeCalls(w io.Writer, title string, calls []*Call) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if len(calls) == 0 {
+		fmt.Fprintf(w, "%snone\n", preIndent)
+	}
+	writeCallTree(w, calls, preIndent)
+}
+
+func writeCallTree(w io.Writer, calls []*Call, prefix string) {
+	for _, c := range calls {
+		fmt.Fprintf(w, "%s%s (%s)\n", prefix, c.Name, c.Site)
+		if c.Synopsis != "" {
+			fmt.Fprintf(w, "%s  %s\n", prefix, c.Synopsis)
+		}
+		writeCallTree(w, c.Calls, prefix+preIndent)
+	}
+}
+
+// renderText formats a doc comment as plain text, wrapping paragraphs and
+// list items at width code points.  It understands the Go 1.19 doc comment
+// syntax: headings, lists, code blocks, links and link definitions.
+func renderText(text string, width int) []byte {
+	pr := &comment.Printer{
+		TextPrefix:     indent,
+		TextCodePrefix: preIndent,
+		TextWidth:      width,
+	}
+	return pr.Text(parseComment(text))
+}
+
+// parseComment parses the text of a doc comment.
+func parseComment(text string) *comment.Doc {
+	p := &comment.Parser{
+		// We don't know the symbols of the documented package here,
+		// so treat every [Name] and [Recv.Name] as a doc link.
+		// This is what the reader intended in the vast majority of cases.
+		LookupSym: func(recv, name string) bool { return true },
+	}
+	return p.Parse(text)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRenderText(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "render", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no inputs in testdata/render")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			src, err := ioutil.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := renderText(string(src), 60)
+
+			golden := strings.TrimSuffix(input, ".txt") + ".golden"
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("rendering %s:\nwant:\n%s\ngot:\n%s", input, want, got)
+			}
+		})
+	}
+}
+
+func TestDocString(t *testing.T) {
+	d := &Doc{
+		Import: "io",
+		Decl:   "type Reader interface{ ... }",
+		Doc:    "Reader reads.\n\n  - one\n  - two\n",
+	}
+	want := "import \"io\"\n\ntype Reader interface{ ... }\n\nReader reads.\n\n  - one\n  - two\n"
+	if got := d.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	undocumented := &Doc{Decl: "func F()"}
+	if got := undocumented.String(); got != "func F()\n\nUndocumented.\n" {
+		t.Errorf("unexpected output for undocumented item: %q", got)
+	}
+}
+
+func TestDocStringSections(t *testing.T) {
+	d := &Doc{Decl: "func F()", Doc: "F does nothing.\n", Refs: []Ref{}, Callers: []*Call{}}
+	want := "func F()\n\nF does nothing.\n\nNo references found.\n\nCallers:\n    none\n"
+	if got := d.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// excludedReason explains why filename was not among the files of the
+// packages loaded for it.
+func excludedReason(filename string, overlay map[string][]byte, bc *BuildContext, pkgs []*packages.Package) string {
+	src, err := readSource(filename, overlay)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "no such file"
+		}
+		return err.Error()
+	}
+	base := filepath.Base(filename)
+	if !strings.HasSuffix(base, ".go") {
+		return "it is not a Go file"
+	}
+	if strings.HasPrefix(base, "_") || strings.HasPrefix(base, ".") {
+		return "files whose names begin with _ or . are ignored"
+	}
+
+	ctxt := bc.context(filename, src)
+	match, err := ctxt.MatchFile(filepath.Dir(filename), base)
+	if err != nil {
+		return err.Error()
+	}
+	if !match {
+		if line := buildConstraint(src); line != "" {
+			return fmt.Sprintf("it is excluded by the build constraint %q for %s", line, bc)
+		}
+		if !ctxt.CgoEnabled && importsC(src) {
+			return `it imports "C" but cgo is disabled`
+		}
+		return fmt.Sprintf("its file name excludes it for %s", bc)
+	}
+
+	for _, pkg := range pkgs {
+		for _, err := range pkg.Errors {
+			return err.Msg
+		}
+	}
+	return "it does not belong to any package that could be loaded (is it outside the module?)"
+}
+
+// buildConstraint returns the first build constraint line in the header
+// of a Go source file, or "" if there is none.
+func buildConstraint(src []byte) string {
+	var plusBuild string
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if constraint.IsGoBuild(line) {
+			return line
+		}
+		if plusBuild == "" && constraint.IsPlusBuild(line) {
+			plusBuild = line
+		}
+	}
+	return plusBuild
+}
+
+// importsC reports whether the Go source src imports "C".
+func importsC(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// exportMode loads the metadata of packages and their dependencies, along
+// with the compiler export data of the dependencies, for -export.
+const exportMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedExportFile
+
+// checkFromExport type-checks each package of pkgs, loaded in exportMode,
+// from source, taking the types of its dependencies from their compiler
+// export data.  Its files are read from the overlay or disk and parsed by
+// parseFile, as with packages.Config.ParseFile.
+func checkFromExport(pkgs []*packages.Package, overlay map[string][]byte, sizes types.Sizes,
+	parseFile func(*token.FileSet, string, []byte) (*ast.File, error)) {
+	fset := token.NewFileSet()
+	for _, pkg := range pkgs {
+		pkg.Fset = fset
+		for _, name := range pkg.CompiledGoFiles {
+			src, err := readSource(name, overlay)
+			if err != nil {
+				pkg.Errors = append(pkg.Errors, packages.Error{Msg: err.Error(), Kind: packages.ParseError})
+				pkg.IllTyped = true
+				continue
+			}
+			f, err := parseFile(fset, name, src)
+			if err != nil {
+				pkg.IllTyped = true
+			}
+			if f != nil {
+				pkg.Syntax = append(pkg.Syntax, f)
+			}
+		}
+
+		// the export data of a package refers to its dependencies by
+		// package path, which is unique among those of a single package
+		exports := make(map[string]string)
+		packages.Visit([]*packages.Package{pkg}, nil, func(p *packages.Package) {
+			exports[p.PkgPath] = p.ExportFile
+		})
+		imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+			if exports[path] == "" {
+				return nil, fmt.Errorf("no export data for %s", path)
+			}
+			return os.Open(exports[path])
+		})
+		pkg.TypesInfo = &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		}
+		pkg.TypesSizes = sizes
+		conf := &types.Config{
+			Importer: importerFunc(func(path string) (*types.Package, error) {
+				if path == "unsafe" {
+					return types.Unsafe, nil
+				}
+				dep := pkg.Imports[path]
+				if dep == nil {
+					return nil, fmt.Errorf("no metadata for %s", path)
+				}
+				t, err := imp.Import(dep.PkgPath)
+				if err != nil {
+					return nil, err
+				}
+				if dep.Types == nil {
+					dep.Types = t
+				}
+				return t, nil
+			}),
+			Error: func(err error) {
+				pkg.IllTyped = true
+				if err, ok := err.(types.Error); ok {
+					pkg.Errors = append(pkg.Errors, packages.Error{
+						Pos:  err.Fset.Position(err.Pos).String(),
+						Msg:  err.Msg,
+						Kind: packages.TypeError,
+					})
+				}
+			},
+			Sizes: sizes,
+		}
+		pkg.Types, _ = conf.Check(pkg.PkgPath, fset, pkg.Syntax, pkg.TypesInfo)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/build"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestExportData(t *testing.T) {
+	*exportData = true
+	defer func() { *exportData = false }()
+
+	dir := filepath.Join(".", "testdata", "package")
+	mods := []packagestest.Module{
+		{Name: "somepkg", Files: packagestest.MustCopyFileTree(dir)},
+	}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		getDoc := func(p token.Position) *Doc {
+			t.Helper()
+			doc, err := Run(p.Filename, p.Offset, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return doc
+		}
+		if err := exported.Expect(map[string]interface{}{
+			"doc": func(p token.Position, doc string) {
+				if got := getDoc(p).Doc; !strings.HasPrefix(got, doc) {
+					t.Errorf("expected prefix %q in %q", doc, got)
+				}
+			},
+			"pkg": func(p token.Position, pkg string) {
+				if got := getDoc(p).Pkg; got != pkg {
+					t.Errorf("want %q, got %q", pkg, got)
+				}
+			},
+			"decl": func(p token.Position, decl string) {
+				if got := getDoc(p).Decl; got != decl {
+					t.Errorf("want %q, got %q", decl, got)
+				}
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// BenchmarkLoad compares loading all dependencies from source with
+// loading them from export data, documenting a use of time.Now in
+// net/http, which has a large dependency graph.
+func BenchmarkLoad(b *testing.B) {
+	filename := filepath.Join(build.Default.GOROOT, "src", "net", "http", "server.go")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		b.Skip(err)
+	}
+	offset := strings.Index(string(src), "time.Now()") + len("time.")
+	for _, export := range []bool{false, true} {
+		name := "source"
+		if export {
+			name = "export"
+		}
+		b.Run(name, func(b *testing.B) {
+			*exportData = export
+			defer func() { *exportData = false }()
+			for i := 0; i < b.N; i++ {
+				d, err := Run(filename, offset, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if d.Name != "Now" {
+					b.Fatalf("documented %s, want Now", d.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Expr describes the type and value of an expression.
+type Expr struct {
+	Expr        string `json:"expr"`
+	Type        string `json:"type"`
+	Value       string `json:"value,omitempty"`       // constant value, if the expression is constant
+	DefaultType string `json:"defaultType,omitempty"` // default type of an untyped constant
+}
+
+func (e *Expr) String() string {
+	s := fmt.Sprintf("%s: %s", e.Expr, e.Type)
+	if e.Value != "" {
+		s += " = " + e.Value
+	}
+	if e.DefaultType != "" {
+		s += fmt.Sprintf(" (default type %s)", e.DefaultType)
+	}
+	return s
+}
+
+// ExprDoc documents the smallest expression in nodes that has a type:
+// its type, its value if it is constant, and the documentation of its
+// (default) type.
+func ExprDoc(pkg *packages.Package, nodes []ast.Node) (*Doc, error) {
+	if pkg.TypesInfo == nil {
+		return nil, errors.New("gogetdoc: no typed expression found")
+	}
+	for _, node := range nodes {
+		e, ok := node.(ast.Expr)
+		if !ok {
+			continue
+		}
+		tv, ok := pkg.TypesInfo.Types[e]
+		if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+			continue
+		}
+		return exprDoc(pkg, e, tv)
+	}
+	return nil, errors.New("gogetdoc: no typed expression found")
+}
+
+func exprDoc(pkg *packages.Package, e ast.Expr, tv types.TypeAndValue) (*Doc, error) {
+	info, err := newExpr(pkg, e, tv)
+	if err != nil {
+		return nil, err
+	}
+	typ := tv.Type
+	if b, ok := typ.(*types.Basic); ok && b.Info()&types.IsUntyped != 0 {
+		typ = types.Default(typ)
+	}
+
+	d := &Doc{Name: info.Expr, Decl: info.Type}
+	if obj := typeObject(typ); obj != nil {
+		if od, err := ObjectDoc(obj, pkg); err == nil {
+			d = od
+		}
+	}
+	d.Expr = info
+	return d, nil
+}
+
+// newExpr describes the expression e, whose type and value are tv.
+func newExpr(pkg *packages.Package, e ast.Expr, tv types.TypeAndValue) (*Expr, error) {
+	qual := qualifier(pkg.Types)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, pkg.Fset, e); err != nil {
+		return nil, err
+	}
+	info := &Expr{
+		Expr: buf.String(),
+		Type: types.TypeString(tv.Type, qual),
+	}
+	if tv.Value != nil {
+		info.Value = tv.Value.ExactString()
+	}
+	if b, ok := tv.Type.(*types.Basic); ok && b.Info()&types.IsUntyped != 0 {
+		if def := types.Default(b); def != tv.Type {
+			info.DefaultType = types.TypeString(def, qual)
+		}
+	}
+	return info, nil
+}
+
+// typeObject returns the object declaring typ, a basic or (pointer to a)
+// named type, or nil if it has none.
+func typeObject(typ types.Type) types.Object {
+	if b, ok := typ.(*types.Basic); ok {
+		return types.Universe.Lookup(b.Name())
+	}
+	if named := namedType(typ); named != nil {
+		return named.Obj()
+	}
+	return nil
+}
+
+// methodFunc describes the function value denoted by the selector of id
+// when it is used as a method value (x.M) or a method expression (T.M)
+// rather than called, along with the kind of the selection.  For method
+// expressions, the receiver is the first parameter of the function.
+// It returns a nil Expr otherwise.
+func methodFunc(pkg *packages.Package, id *ast.Ident, path []ast.Node) (*Expr, string, error) {
+	if len(path) == 0 {
+		return nil, "", nil
+	}
+	sel, ok := path[0].(*ast.SelectorExpr)
+	if !ok || sel.Sel != id {
+		return nil, "", nil
+	}
+	var kind string
+	switch s := pkg.TypesInfo.Selections[sel]; {
+	case s == nil:
+		return nil, "", nil
+	case s.Kind() == types.MethodVal:
+		kind = "method value"
+	case s.Kind() == types.MethodExpr:
+		kind = "method expression"
+	default:
+		return nil, "", nil
+	}
+	if len(path) > 1 {
+		if call, ok := path[1].(*ast.CallExpr); ok && call.Fun == sel {
+			return nil, "", nil
+		}
+	}
+	tv, ok := pkg.TypesInfo.Types[sel]
+	if !ok {
+		return nil, "", nil
+	}
+	expr, err := newExpr(pkg, sel, tv)
+	return expr, kind, err
+}
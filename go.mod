@@ -1,3 +1,10 @@
 module github.com/zmb3/gogetdoc
 
-require golang.org/x/tools v0.0.0-20181207195948-8634b1ecd393
+go 1.22.0
+
+require (
+	golang.org/x/mod v0.21.0
+	golang.org/x/tools v0.26.0
+)
+
+require golang.org/x/sync v0.8.0 // indirect
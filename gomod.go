@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// goMod holds the parts of a go.mod file that gogetdoc cares about.
+type goMod struct {
+	Dir     string            // directory containing the go.mod file
+	Module  string            // module path
+	Go      string            // go directive, e.g. "1.21", or empty if absent
+	Require map[string]string // module path to required version
+	Replace []modReplace
+	Use     []string // module directories, in go.work files
+}
+
+// modReplace is a replace directive.  Old.Version is empty if the directive
+// applies to all versions, and New.Version is empty for directory replacements.
+type modReplace struct {
+	Old, New modVersion
+}
+
+type modVersion struct {
+	Path, Version string
+}
+
+// findGoMod returns the path of the go.mod file governing dir,
+// or the empty string if dir is not inside a module.
+func findGoMod(dir string) string {
+	return findUp(dir, "go.mod")
+}
+
+// findGoWork returns the path of the go.work file in effect for dir, as
+// given by the -workspace flag or GOWORK or else found in dir or a parent,
+// or the empty string if there is none or workspaces are off.
+func findGoWork(dir string) string {
+	gowork := *workspace
+	if gowork == "" {
+		gowork = os.Getenv("GOWORK")
+	}
+	switch gowork {
+	case "off":
+		return ""
+	case "":
+		return findUp(dir, "go.work")
+	}
+	if abs, err := filepath.Abs(gowork); err == nil {
+		return abs
+	}
+	return gowork
+}
+
+// workspaceEnv returns the GOWORK setting for loading the packages in
+// dir, so that the go command uses the workspace of dir rather than that
+// of the current directory.
+func workspaceEnv(dir string) []string {
+	if gowork := findGoWork(dir); gowork != "" {
+		return []string{"GOWORK=" + gowork}
+	}
+	if *workspace == "off" {
+		return []string{"GOWORK=off"}
+	}
+	return nil
+}
+
+// findUp returns the path of the file with the given name in dir or the
+// closest of its parents, or the empty string if there is none.
+func findUp(dir, name string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && !fi.IsDir() {
+			return filepath.Join(dir, name)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseGoMod reads the go.mod (or go.work) file at path.  It is
+// deliberately forgiving: lines that it does not understand are ignored.
+func parseGoMod(path string) (*goMod, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mod := &goMod{Dir: filepath.Dir(path), Require: make(map[string]string)}
+	block := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := modFields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			mod.Module = fields[1]
+		case "go":
+			mod.Go = fields[1]
+		case "require":
+			if len(fields) >= 3 {
+				mod.Require[fields[1]] = fields[2]
+			}
+		case "use":
+			mod.Use = append(mod.Use, fields[1])
+		case "replace":
+			if r, ok := parseReplace(fields[1:]); ok {
+				mod.Replace = append(mod.Replace, r)
+			}
+		}
+	}
+	return mod, s.Err()
+}
+
+// parseReplace parses the fields of a replace directive:
+// old [version] => new [version].
+func parseReplace(fields []string) (modReplace, bool) {
+	var r modReplace
+	arrow := -1
+	for i, f := range fields {
+		if f == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || arrow == len(fields)-1 {
+		return r, false
+	}
+	r.Old.Path = fields[0]
+	if arrow == 2 {
+		r.Old.Version = fields[1]
+	}
+	r.New.Path = fields[arrow+1]
+	if len(fields) > arrow+2 {
+		r.New.Version = fields[arrow+2]
+	}
+	return r, true
+}
+
+// isDirReplacement reports whether the replacement is a local directory
+// rather than another module.
+func (r modReplace) isDirReplacement() bool {
+	return r.New.Version == "" && (filepath.IsAbs(r.New.Path) || strings.HasPrefix(r.New.Path, "./") || strings.HasPrefix(r.New.Path, "../"))
+}
+
+// modFields splits a go.mod line into fields, dropping comments and
+// unquoting quoted strings.
+func modFields(line string) []string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		fields[i] = strings.Trim(f, "\"`")
+	}
+	return fields
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/parser"
 	"go/printer"
 	"go/token"
 	"go/types"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/tools/go/ast/astutil"
@@ -35,7 +38,7 @@ func findVarSpec(decl *ast.GenDecl, pos token.Pos) *ast.ValueSpec {
 	return nil
 }
 
-func formatNode(n ast.Node, obj types.Object, prog *packages.Package) string {
+func formatNode(n ast.Node, obj types.Object, fset *token.FileSet) string {
 	// fmt.Printf("formatting %T node\n", n)
 	qual := func(p *types.Package) string { return "" }
 
@@ -102,7 +105,7 @@ func formatNode(n ast.Node, obj types.Object, prog *packages.Package) string {
 
 	buf := &bytes.Buffer{}
 	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
-	err := cfg.Fprint(buf, prog.Fset, nc)
+	err := cfg.Fprint(buf, fset, nc)
 	if err != nil {
 		return obj.String()
 	}
@@ -119,7 +122,28 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 	if v, ok := obj.(*types.Var); ok && v.Anonymous() {
 		obj = info.Uses[id]
 	}
+	return ObjectDoc(obj, pkg)
+}
 
+// ObjectDoc gets the documentation for an object referenced from pkg.
+func ObjectDoc(obj types.Object, pkg *packages.Package) (*Doc, error) {
+	doc, err := objectDoc(obj, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := obj.(*types.Var); ok && typeSwitchClause(pkg.TypesInfo, v) != nil {
+		// the variable of a type switch clause has no doc of its own,
+		// so document its type in that clause instead
+		if to := typeObject(v.Type()); to != nil {
+			if td, err := objectDoc(to, pkg); err == nil {
+				doc.Doc = td.Doc
+			}
+		}
+	}
+	return doc, nil
+}
+
+func objectDoc(obj types.Object, pkg *packages.Package) (*Doc, error) {
 	var pos string
 	if p := obj.Pos(); p.IsValid() {
 		pos = pkg.Fset.Position(p).String()
@@ -136,7 +160,17 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 		return PackageDoc(pkg, p.Imported().Path())
 	}
 
+	fset := pkg.Fset
 	nodes := pathEnclosingInterval(pkg, obj.Pos(), obj.Pos())
+	if len(nodes) == 0 && obj.Pkg() != nil && obj.Pos().IsValid() {
+		// the object's package was loaded from export data,
+		// so parse just the file that declares it
+		p := pkg.Fset.Position(obj.Pos())
+		if declFset, path := declPath(p.Filename, p.Line, obj.Name()); path != nil {
+			fset, nodes = declFset, path
+			pos = fset.Position(path[0].Pos()).String()
+		}
+	}
 	if len(nodes) == 0 {
 		// special case - builtins
 		doc, decl := findInBuiltin(obj.Name(), obj, pkg)
@@ -148,6 +182,9 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 				Doc:    doc,
 				Decl:   decl,
 				Pos:    pos,
+				Kind:   objectKind(obj),
+				Module: moduleOf(pkg, filepath.Join(build.Default.GOROOT, "src", "builtin", "builtin.go"), "builtin"),
+				obj:    obj,
 			}, nil
 		}
 		return nil, fmt.Errorf("no documentation found for %s", obj.Name())
@@ -167,8 +204,12 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 			Import: stripVendorFromImportPath(pkgPath),
 			Pkg:    pkgName,
 			Name:   obj.Name(),
-			Decl:   formatNode(node, obj, pkg),
+			Decl:   formatNode(node, obj, fset),
 			Pos:    pos,
+			Kind:   objectKind(obj),
+			decl:   sourceNode(nodes),
+			fset:   fset,
+			obj:    obj,
 		}
 		break
 	}
@@ -177,56 +218,68 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 		return nil, fmt.Errorf("no documentation found for %s", obj.Name())
 	}
 
-	for _, node := range nodes {
-		//fmt.Printf("for %s: found %T\n%#v\n", id.Name, node, node)
-		switch n := node.(type) {
+	declFile := fset.Position(nodes[0].Pos()).Filename
+	doc.Module = moduleOf(pkg, declFile, pkgPath)
+	if isStdlibFile(declFile) {
+		if sym := apiSymbolName(obj, enclosingTypeName(nodes)); sym != "" {
+			doc.Since = apiSince(pkgPath, sym)
+		}
+	}
+
+	doc.Doc = declDoc(nodes)
+	if c, ok := obj.(*types.Const); ok {
+		doc.Doc += fmt.Sprintf("\nConstant Value: %s", c.Val().ExactString())
+	}
+	return doc, nil
+}
+
+// declDoc returns the doc comment of the declaration at the start of path,
+// falling back to its line comment.  Identifiers declared other than by a
+// declaration, such as by a short variable declaration, have none.
+func declDoc(path []ast.Node) string {
+	for _, n := range path {
+		switch n := n.(type) {
 		case *ast.Ident:
 			continue
 		case *ast.FuncDecl:
-			doc.Doc = n.Doc.Text()
-			return doc, nil
+			return n.Doc.Text()
 		case *ast.Field:
 			if n.Doc != nil {
-				doc.Doc = n.Doc.Text()
-			} else if n.Comment != nil {
-				doc.Doc = n.Comment.Text()
+				return n.Doc.Text()
 			}
-			return doc, nil
+			return n.Comment.Text()
 		case *ast.TypeSpec:
 			if n.Doc != nil {
-				doc.Doc = n.Doc.Text()
-				return doc, nil
+				return n.Doc.Text()
 			}
 			if n.Comment != nil {
-				doc.Doc = n.Comment.Text()
-				return doc, nil
+				return n.Comment.Text()
 			}
 		case *ast.ValueSpec:
 			if n.Doc != nil {
-				doc.Doc = n.Doc.Text()
-				return doc, nil
+				return n.Doc.Text()
 			}
 			if n.Comment != nil {
-				doc.Doc = n.Comment.Text()
-				return doc, nil
+				return n.Comment.Text()
 			}
 		case *ast.GenDecl:
-			constValue := ""
-			if c, ok := obj.(*types.Const); ok {
-				constValue = c.Val().ExactString()
-			}
-			if doc.Doc == "" && n.Doc != nil {
-				doc.Doc = n.Doc.Text()
-			}
-			if constValue != "" {
-				doc.Doc += fmt.Sprintf("\nConstant Value: %s", constValue)
-			}
-			return doc, nil
+			return n.Doc.Text()
 		default:
-			return doc, nil
+			return ""
 		}
 	}
-	return doc, nil
+	return ""
+}
+
+// enclosingTypeName returns the name of the innermost type declaration
+// in the path, or the empty string if there is none.
+func enclosingTypeName(path []ast.Node) string {
+	for _, n := range path {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			return spec.Name.Name
+		}
+	}
+	return ""
 }
 
 // pathEnclosingInterval returns ast.Node of the package that
@@ -236,6 +289,16 @@ func IdentDoc(id *ast.Ident, info *types.Info, pkg *packages.Package) (*Doc, err
 //
 // Modified from golang.org/x/tools/go/loader.
 func pathEnclosingInterval(initPkg *packages.Package, start, end token.Pos) []ast.Node {
+	return pathEnclosingIntervalSeen(initPkg, start, end, make(map[*packages.Package]bool))
+}
+
+// pathEnclosingIntervalSeen is pathEnclosingInterval, skipping the packages
+// in seen, which are shared among those importing them.
+func pathEnclosingIntervalSeen(initPkg *packages.Package, start, end token.Pos, seen map[*packages.Package]bool) []ast.Node {
+	if seen[initPkg] {
+		return nil
+	}
+	seen[initPkg] = true
 	for _, f := range initPkg.Syntax {
 		if f.Pos() == token.NoPos {
 			// This can happen if the parser saw
@@ -252,7 +315,7 @@ func pathEnclosingInterval(initPkg *packages.Package, start, end token.Pos) []as
 	}
 
 	for _, p := range initPkg.Imports {
-		if path := pathEnclosingInterval(p, start, end); path != nil {
+		if path := pathEnclosingIntervalSeen(p, start, end, seen); path != nil {
 			return path
 		}
 	}
@@ -260,6 +323,49 @@ func pathEnclosingInterval(initPkg *packages.Package, start, end token.Pos) []as
 	return nil
 }
 
+// declPath parses filename and returns the path of nodes enclosing the
+// identifier that declares name on the given line, or nil if there is none.
+// It is used for objects declared outside of the loaded syntax: those of
+// dependencies loaded from export data with -export, and functions outside
+// of the module in the call hierarchy.
+func declPath(filename string, line int, name string) (*token.FileSet, []ast.Node) {
+	// export data records standard library files relative to $GOROOT
+	filename = strings.Replace(filename, "$GOROOT", build.Default.GOROOT, 1)
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if f == nil {
+		return nil, nil
+	}
+	var found *ast.Ident
+	match := func(id *ast.Ident) {
+		if found == nil && id != nil && id.Name == name && fset.Position(id.Pos()).Line == line {
+			found = id
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			match(n.Name)
+		case *ast.TypeSpec:
+			match(n.Name)
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				match(id)
+			}
+		case *ast.Field:
+			for _, id := range n.Names {
+				match(id)
+			}
+		}
+		return found == nil
+	})
+	if found == nil {
+		return nil, nil
+	}
+	path, _ := astutil.PathEnclosingInterval(f, found.Pos(), found.End())
+	return fset, path
+}
+
 func tokenFileContainsPos(f *token.File, pos token.Pos) bool {
 	p := int(pos)
 	base := f.Base()
@@ -53,9 +53,149 @@ func TestIdent(t *testing.T) {
 		}
 
 		if expectErr := exported.Expect(map[string]interface{}{
-			"doc":  func(p token.Position, doc string) { pcmp(doc, getDoc(p).Doc) },
-			"pkg":  func(p token.Position, pkg string) { cmp(pkg, getDoc(p).Pkg) },
-			"decl": func(p token.Position, decl string) { cmp(decl, getDoc(p).Decl) },
+			"doc":   func(p token.Position, doc string) { pcmp(doc, getDoc(p).Doc) },
+			"pkg":   func(p token.Position, pkg string) { cmp(pkg, getDoc(p).Pkg) },
+			"decl":  func(p token.Position, decl string) { cmp(decl, getDoc(p).Decl) },
+			"since": func(p token.Position, since string) { cmp(since, getDoc(p).Since) },
+			"callers": func(p token.Position, names []string) {
+				*showCallers = true
+				defer func() { *showCallers = false }()
+				callNames(t, names, getDoc(p).Callers)
+			},
+			"callees": func(p token.Position, names []string) {
+				*showCallees = true
+				defer func() { *showCallees = false }()
+				callNames(t, names, getDoc(p).Callees)
+			},
+			"signature": func(p token.Position, label, param string) {
+				*signatureHelp = true
+				defer func() { *signatureHelp = false }()
+				sig := getDoc(p).Signature
+				if sig == nil {
+					t.Fatalf("no signature at %v", p)
+				}
+				cmp(label, sig.Label)
+				if sig.Active < 0 {
+					t.Errorf("no active parameter, want %s", param)
+				} else {
+					cmp(param, sig.Params[sig.Active].Name)
+				}
+			},
+			"members": func(p token.Position, name string, want []string) {
+				*listMembers = true
+				defer func() { *listMembers = false }()
+				d := getDoc(p)
+				cmp(name, d.Name)
+				var got []string
+				for _, m := range d.Members {
+					got = append(got, m.Name)
+				}
+				cmp(strings.Join(want, " "), strings.Join(got, " "))
+			},
+			"member": func(p token.Position, name, synopsis string) {
+				*listMembers = true
+				defer func() { *listMembers = false }()
+				d := getDoc(p)
+				found := false
+				for _, m := range d.Members {
+					if !isUpper(m.Name) && d.Import != "somepkg" {
+						t.Errorf("unexported member %s of %s.%s is not accessible", m.Name, d.Pkg, d.Name)
+					}
+					if m.Name == name {
+						found = true
+						pcmp(synopsis, m.Synopsis)
+					}
+				}
+				if !found {
+					t.Errorf("no member %s in %v", name, d.Members)
+				}
+			},
+			"expr": func(p token.Position, expr, typ, value, defaultType string) {
+				*exprMode = true
+				defer func() { *exprMode = false }()
+				d := getDoc(p)
+				if d.Expr == nil {
+					t.Fatalf("no expression at %v", p)
+				}
+				cmp(expr, d.Expr.Expr)
+				cmp(typ, d.Expr.Type)
+				cmp(value, d.Expr.Value)
+				cmp(defaultType, d.Expr.DefaultType)
+			},
+			"exprdoc": func(p token.Position, doc string) {
+				*exprMode = true
+				defer func() { *exprMode = false }()
+				pcmp(doc, getDoc(p).Doc)
+			},
+			"selection": func(fset *token.FileSet, r packagestest.Range, doc, expr, typ string) {
+				start, end := fset.Position(r.Start), fset.Position(r.End)
+				d, err := RunRange(start.Filename, start.Offset, end.Offset, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pcmp(doc, d.Doc)
+				if expr == "" {
+					if d.Expr != nil {
+						t.Errorf("unexpected expression %v at %v", d.Expr, start)
+					}
+					return
+				}
+				if d.Expr == nil {
+					t.Fatalf("no expression at %v", start)
+				}
+				cmp(expr, d.Expr.Expr)
+				cmp(typ, d.Expr.Type)
+			},
+			"functype": func(p token.Position, typ string) {
+				d := getDoc(p)
+				if typ == "" {
+					if d.Expr != nil {
+						t.Errorf("unexpected func type %v at %v", d.Expr, p)
+					}
+					return
+				}
+				if d.Expr == nil {
+					t.Fatalf("no func type at %v", p)
+				}
+				cmp(typ, d.Expr.Type)
+			},
+			"all": func(p token.Position, kinds []string) {
+				docs, err := RunAll(p.Filename, p.Offset, p.Offset, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, d := range docs {
+					got = append(got, d.Kind)
+				}
+				cmp(strings.Join(kinds, ", "), strings.Join(got, ", "))
+			},
+			"refs": func(p token.Position, funcs []string) {
+				*showRefs = true
+				defer func() { *showRefs = false }()
+				refs := getDoc(p).Refs
+				if len(refs) != len(funcs) {
+					t.Fatalf("want %d refs, got %d: %v", len(funcs), len(refs), refs)
+				}
+				for i, ref := range refs {
+					cmp(funcs[i], ref.Func)
+				}
+			},
+			"body": func(p token.Position, source string) {
+				*showBody = true
+				defer func() { *showBody = false }()
+				cmp(source, getDoc(p).Source)
+			},
+			"module": func(p token.Position, kind string) {
+				if kind == "main" && exporter == packagestest.GOPATH {
+					kind = "gopath"
+				}
+				if m := getDoc(p).Module; m == nil {
+					t.Errorf("no module information, want kind %q", kind)
+				} else {
+					cmp(kind, m.Kind)
+				}
+			},
 			"const": func(p token.Position, val string) {
 				d := getDoc(p)
 				needle := "Constant Value: " + val
@@ -111,6 +251,7 @@ func TestVendoredCode(t *testing.T) {
 		"import": func(p token.Position, path string) { compare(path, getDoc(p).Import) },
 		"decl":   func(p token.Position, decl string) { compare(decl, getDoc(p).Decl) },
 		"doc":    func(p token.Position, doc string) { compare(doc, getDoc(p).Doc) },
+		"module": func(p token.Position, kind string) { compare(kind, getDoc(p).Module.Kind) },
 	}); expectErr != nil {
 		t.Fatal(expectErr)
 	}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -11,14 +12,17 @@ import (
 	"go/build"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"runtime/pprof"
 	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/buildutil"
@@ -27,13 +31,37 @@ import (
 
 var (
 	cpuprofile           = flag.String("cpuprofile", "", "write cpu profile to file")
-	pos                  = flag.String("pos", "", "Filename and byte offset of item to document, e.g. foo.go:#123")
+	pos                  = flag.String("pos", "", "Filename and byte offset of item to document, e.g. foo.go:#123, or a selection, e.g. foo.go:#123,#140")
 	modified             = flag.Bool("modified", false, "read an archive of modified files from standard input")
+	modifiedJSON         = flag.Bool("modified-json", false, "read a JSON object mapping modified files to their contents from standard input")
+	overlayFile          = flag.String("overlay", "", "read a JSON file replacing files with the contents of others, as for go build -overlay")
 	linelength           = flag.Int("linelength", 80, "maximum length of a line in the output (in Unicode code points)")
 	jsonOutput           = flag.Bool("json", false, "enable extended JSON output")
 	showUnexportedFields = flag.Bool("u", false, "show unexported fields")
+	blocksOutput         = flag.Bool("blocks", false, "include the doc comment as structured blocks in JSON output")
+	showBody             = flag.Bool("body", false, "include the full source of the declaration")
+	bodyLines            = flag.Int("bodylines", 50, "maximum number of lines of source to include with -body (0 for no limit)")
+	showRefs             = flag.Bool("refs", false, "list the uses of the identifier in the current module")
+	showCallers          = flag.Bool("callers", false, "list the functions in the current module that call the function")
+	showCallees          = flag.Bool("callees", false, "list the functions called by the function")
+	callDepth            = flag.Int("depth", 1, "depth of the call hierarchy for -callers and -callees")
+	signatureHelp        = flag.Bool("signature", false, "document the function whose call arguments contain the cursor")
+	listMembers          = flag.Bool("members", false, "list the fields and methods of the expression before the selector at the cursor")
+	exprMode             = flag.Bool("expr", false, "show the type and constant value of the smallest expression at the cursor")
+	exportData           = flag.Bool("export", false, "type-check only the current package from source, loading dependencies from compiler export data (faster)")
+	goos                 = flag.String("goos", "", "GOOS to load packages for (default: the environment, or one that builds the file)")
+	goarch               = flag.String("goarch", "", "GOARCH to load packages for (default: the environment, or one that builds the file)")
+	loadTimeout          = flag.Duration("timeout", 0, "maximum time to spend loading the package, such as 5s (0 for no limit)")
+	buildFlags           = flag.String("buildflags", "", "additional flags for the go command that loads packages, separated by spaces, such as \"-mod=vendor -race\"")
+	workDir              = flag.String("dir", "", "directory to run the go command in (default: the current directory)")
+	workspace            = flag.String("workspace", "", "go.work file to load packages with, or off to ignore go.work files (default: $GOWORK, or the go.work file above the file)")
+	pkgVariant           = flag.String("pkg", "", "package variant to load a file in: normal, test (with its _test.go files), xtest (an external _test package) or a package ID (default: one that type-checks)")
+	allDocs              = flag.Bool("all", false, "document everything an ambiguous identifier denotes, such as both the type and field of an embedded field")
 )
 
+// extraEnv is the environment set by -env.
+var extraEnv []string
+
 var archiveReader io.Reader = os.Stdin
 
 const modifiedUsage = `
@@ -41,6 +69,12 @@ The archive format for the -modified flag consists of the file name, followed
 by a newline, the decimal file size, another newline, and the contents of the file.
 
 This allows editors to supply gogetdoc with the contents of their unsaved buffers.
+
+With -modified-json, standard input is instead a JSON object mapping file
+names to their contents, such as {"/path/to/foo.go": "package foo\n..."}.
+The -overlay flag takes a file in the format of go build -overlay,
+{"Replace": {"/path/to/foo.go": "/tmp/foo.go"}}, whose file contents are
+read from the named files.
 `
 
 const debugAST = false
@@ -57,6 +91,7 @@ func main() {
 	log.SetOutput(ioutil.Discard)
 
 	flag.Var((*buildutil.TagsFlag)(&build.Default.BuildTags), "tags", buildutil.TagsFlagDoc)
+	flag.Var((*envFlag)(&extraEnv), "env", "set an environment variable for the go command that loads packages, such as CGO_ENABLED=0 (repeatable)")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s\n", os.Args[0])
 		flag.PrintDefaults()
@@ -73,24 +108,51 @@ func main() {
 		}
 		defer pprof.StopCPUProfile()
 	}
-	filename, offset, err := parsePos(*pos)
+	filename, start, end, err := parseRange(*pos)
+	if err != nil {
+		fatal(err)
+	}
+	// the modified files are keyed by absolute file name
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+
+	overlay, err := readOverlay()
 	if err != nil {
 		fatal(err)
 	}
 
-	var overlay map[string][]byte
-	if *modified {
-		overlay, err = buildutil.ParseOverlayArchive(archiveReader)
+	if *allDocs {
+		docs, err := RunAll(filename, start, end, overlay)
 		if err != nil {
-			fatal(fmt.Errorf("invalid archive: %v", err))
+			fatal(err)
 		}
+		for i, d := range docs {
+			if *blocksOutput {
+				d.Blocks = docBlocks(d.Doc)
+			}
+			if !*jsonOutput {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Println(d.String())
+			}
+		}
+		if *jsonOutput {
+			json.NewEncoder(os.Stdout).Encode(docs)
+		}
+		return
 	}
 
-	d, err := Run(filename, offset, overlay)
+	d, err := RunRange(filename, start, end, overlay)
 	if err != nil {
 		fatal(err)
 	}
 
+	if *blocksOutput {
+		d.Blocks = docBlocks(d.Doc)
+	}
+
 	if *jsonOutput {
 		json.NewEncoder(os.Stdout).Encode(d)
 	} else {
@@ -102,27 +164,63 @@ func main() {
 // containing the search position.  It can optionally load modified files from
 // an overlay archive.
 func Load(filename string, offset int, overlay map[string][]byte) (*packages.Package, []ast.Node, error) {
+	return LoadRange(context.Background(), filename, offset, offset, overlay)
+}
+
+// LoadRange is like Load, but returns the path to the smallest node
+// enclosing the byte offsets start to end, and gives up when ctx is done.
+func LoadRange(ctx context.Context, filename string, start, end int, overlay map[string][]byte) (*packages.Package, []ast.Node, error) {
+	pkg, nodes, _, err := loadFile(ctx, filename, start, end, overlay)
+	return pkg, nodes, err
+}
+
+// loadFile is like LoadRange, but also returns the build context it used.
+// If the file is not built in the default build context (because of its
+// name or build constraints), it looks for one in which it is.
+func loadFile(ctx context.Context, filename string, start, end int, overlay map[string][]byte) (*packages.Package, []ast.Node, *BuildContext, error) {
+	bc := fileBuildContext(filename, overlay)
+	pkg, nodes, err := load(ctx, &bc, filename, start, end, overlay)
+	// The go command can't load a file outside any package, or loads it
+	// without its sibling files, so check it with them on our own unless
+	// its build constraints exclude it.
+	if (err != nil || pkg.PkgPath == "command-line-arguments" && pkg.IllTyped) && ctx.Err() == nil && *pkgVariant == "" {
+		if src, srcErr := readSource(filename, overlay); srcErr == nil && bc.matches(filename, src) {
+			if adPkg, adNodes, adErr := adHocLoad(&bc, filename, start, end, overlay); adErr == nil {
+				return adPkg, adNodes, &bc, nil
+			}
+		}
+	}
+	return pkg, nodes, &bc, err
+}
+
+// fileBuildContext returns the build context for loading filename: the
+// one given by the flags, or, if that excludes the file, one that
+// satisfies its build constraints.
+func fileBuildContext(filename string, overlay map[string][]byte) BuildContext {
+	bc := defaultBuildContext()
+	if src, err := readSource(filename, overlay); err == nil {
+		if alt := bc.forFile(filename, src); alt != nil {
+			bc = *alt
+		}
+	}
+	return bc
+}
+
+func load(ctx context.Context, bc *BuildContext, filename string, start, end int, overlay map[string][]byte) (*packages.Package, []ast.Node, error) {
 	type result struct {
 		nodes []ast.Node
 		err   error
 	}
-	ch := make(chan result, 1)
+	// the input file is parsed for each package variant containing it
+	var (
+		mu       sync.Mutex
+		results  = make(map[*ast.File]result)
+		parseErr error
+	)
 
 	// Adapted from: https://github.com/ianthehat/godef
-	fstat, fstatErr := os.Stat(filename)
 	parseFile := func(fset *token.FileSet, fname string, src []byte) (*ast.File, error) {
-		var (
-			err error
-			s   os.FileInfo
-		)
-		isInputFile := false
-		if filename == fname {
-			isInputFile = true
-		} else if fstatErr != nil {
-			isInputFile = false
-		} else if s, err = os.Stat(fname); err == nil {
-			isInputFile = os.SameFile(fstat, s)
-		}
+		isInputFile := sameFile(filename, fname)
 
 		mode := parser.ParseComments
 		if isInputFile && debugAST {
@@ -131,99 +229,316 @@ func Load(filename string, offset int, overlay map[string][]byte) (*packages.Pac
 		file, err := parser.ParseFile(fset, fname, src, mode)
 		if file == nil {
 			if isInputFile {
-				ch <- result{nil, err}
+				mu.Lock()
+				parseErr = err
+				mu.Unlock()
 			}
 			return nil, err
 		}
 		var keepFunc *ast.FuncDecl
 		if isInputFile {
-			// find the start of the file (which may be before file.Pos() if there are
-			//  comments before the package clause)
-			start := file.Pos()
-			if len(file.Comments) > 0 && file.Comments[0].Pos() < start {
-				start = file.Comments[0].Pos()
-			}
-
-			pos := start + token.Pos(offset)
-			if pos > file.End() {
-				err := fmt.Errorf("cursor %d is beyond end of file %s (%d)", offset, fname, file.End()-file.Pos())
-				ch <- result{nil, err}
-				return file, err
-			}
-			path, _ := astutil.PathEnclosingInterval(file, pos, pos)
-			if len(path) < 1 {
-				err := fmt.Errorf("offset was not a valid token")
-				ch <- result{nil, err}
-				return nil, err
-			}
-
-			// if we are inside a function, we need to retain that function body
-			// start from the top not the bottom
-			for i := len(path) - 1; i >= 0; i-- {
-				if f, ok := path[i].(*ast.FuncDecl); ok {
-					keepFunc = f
-					break
-				}
-			}
-			ch <- result{path, nil}
-		}
-		// and drop all function bodies that are not relevant so they don't get
-		// type checked
-		for _, decl := range file.Decls {
-			if f, ok := decl.(*ast.FuncDecl); ok && f != keepFunc {
-				f.Body = nil
+			// keep err, so that syntax errors are reported for the package
+			path, keep, pathErr := cursorPath(fname, file, start, end)
+			mu.Lock()
+			results[file] = result{path, pathErr}
+			mu.Unlock()
+			if pathErr != nil {
+				return file, pathErr
 			}
+			keepFunc = keep
 		}
+		stripBodies(file, keepFunc)
 		return file, err
 	}
+	mode := packages.LoadAllSyntax
+	if *exportData {
+		// dependencies come from export data, and the declarations
+		// of their objects are parsed on demand (see objectDoc)
+		mode = exportMode
+	}
 	cfg := &packages.Config{
-		Overlay:   overlay,
-		Mode:      packages.LoadAllSyntax,
-		ParseFile: parseFile,
-		Tests:     strings.HasSuffix(filename, "_test.go"),
+		Context:    ctx,
+		Dir:        bc.Dir,
+		Env:        append(bc.env(), workspaceEnv(filepath.Dir(filename))...),
+		BuildFlags: bc.buildFlags(),
+		Overlay:    overlay,
+		Mode:       mode,
+		ParseFile:  parseFile,
+		Tests:      strings.HasSuffix(filename, "_test.go") || *pkgVariant != "" && *pkgVariant != "normal",
 	}
-	pkgs, err := packages.Load(cfg, fmt.Sprintf("file=%s", filename))
+
+	// type checking does not watch ctx, so wait for the load separately
+	type loaded struct {
+		pkgs []*packages.Package
+		err  error
+	}
+	done := make(chan loaded, 1)
+	go func() {
+		query := filename
+		if bc.Dir != "" {
+			// relative to the current directory, not -dir
+			query, _ = filepath.Abs(filename)
+		}
+		pkgs, err := packages.Load(cfg, fmt.Sprintf("file=%s", query))
+		if err == nil && *exportData {
+			checkFromExport(pkgs, overlay, types.SizesFor("gc", bc.GOARCH), parseFile)
+		}
+		done <- loaded{pkgs, err}
+	}()
+	var l loaded
+	select {
+	case l = <-done:
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("timed out loading the package containing %s", filename)
+	}
+	pkgs, err := l.pkgs, l.err
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("timed out loading the package containing %s", filename)
+		}
 		return nil, nil, fmt.Errorf("cannot load package containing %s: %v", filename, err)
 	}
 	if len(pkgs) == 0 {
-		return nil, nil, fmt.Errorf("no package containing file %s", filename)
+		return nil, nil, fmt.Errorf("no package containing file %s: %s", filename, excludedReason(filename, overlay, bc, pkgs))
+	}
+
+	// the variants that contain the input file, as parsed for them
+	var variants []*packages.Package
+	var paths [][]ast.Node
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Syntax {
+			if r, ok := results[f]; ok {
+				if r.err != nil {
+					return nil, nil, r.err
+				}
+				variants = append(variants, pkg)
+				paths = append(paths, r.nodes)
+				break
+			}
+		}
 	}
-	// Arbitrarily return the first package if there are multiple.
-	// TODO: should the user be able to specify which one?
-	if len(pkgs) > 1 {
-		log.Printf("packages not processed: %v\n", pkgs[1:])
+	if len(variants) == 0 {
+		if parseErr != nil {
+			return nil, nil, parseErr
+		}
+		// the input file was never parsed
+		return nil, nil, fmt.Errorf("%s was not loaded: %s", filename, excludedReason(filename, overlay, bc, pkgs))
+	}
+	i, err := selectVariant(variants, *pkgVariant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v containing %s", err, filename)
 	}
+	return variants[i], paths[i], nil
+}
 
-	r := <-ch
-	if r.err != nil {
-		return nil, nil, err
+// cursorPath returns the path to the smallest node of file enclosing the
+// byte offsets start to end, and the function whose body contains it.
+func cursorPath(fname string, file *ast.File, start, end int) ([]ast.Node, *ast.FuncDecl, error) {
+	pos, endPos := cursorPos(file, start), cursorPos(file, end)
+	if endPos > file.End() {
+		return nil, nil, fmt.Errorf("cursor %d is beyond end of file %s (%d)", end, fname, file.End()-file.Pos())
 	}
-	return pkgs[0], r.nodes, nil
+	path, _ := astutil.PathEnclosingInterval(file, pos, endPos)
+	if len(path) < 1 {
+		return nil, nil, fmt.Errorf("offset was not a valid token")
+	}
+
+	// if we are inside a function, we need to retain that function body
+	// start from the top not the bottom
+	for i := len(path) - 1; i >= 0; i-- {
+		if f, ok := path[i].(*ast.FuncDecl); ok {
+			return path, f, nil
+		}
+	}
+	return path, nil, nil
+}
+
+// stripBodies drops all function bodies in file but that of keep, so
+// they don't get type checked.
+func stripBodies(file *ast.File, keep *ast.FuncDecl) {
+	for _, decl := range file.Decls {
+		if f, ok := decl.(*ast.FuncDecl); ok && f != keep {
+			f.Body = nil
+		}
+	}
+}
+
+// sameFile reports whether the paths a and b name the same file.  Files
+// that exist only in the overlay are compared by name and directory.
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	if errA == nil || errB == nil {
+		return errA == nil && errB == nil && os.SameFile(fa, fb)
+	}
+	if filepath.Base(a) != filepath.Base(b) {
+		return false
+	}
+	dirA, dirB := filepath.Dir(a), filepath.Dir(b)
+	return dirA != a && dirB != b && sameFile(dirA, dirB)
+}
+
+// readSource returns the contents of filename, preferring the overlay
+// of modified files.
+func readSource(filename string, overlay map[string][]byte) ([]byte, error) {
+	if src, ok := overlay[filename]; ok {
+		return src, nil
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// cursorPos returns the position of the byte offset in file.
+func cursorPos(file *ast.File, offset int) token.Pos {
+	// find the start of the file (which may be before file.Pos() if there are
+	//  comments before the package clause)
+	start := file.Pos()
+	if len(file.Comments) > 0 && file.Comments[0].Pos() < start {
+		start = file.Comments[0].Pos()
+	}
+	return start + token.Pos(offset)
 }
 
 // Run is a wrapper for the gogetdoc command.  It is broken out of main for easier testing.
 func Run(filename string, offset int, overlay map[string][]byte) (*Doc, error) {
-	pkg, nodes, err := Load(filename, offset, overlay)
+	return RunRange(filename, offset, offset, overlay)
+}
+
+// RunRange is like Run, but documents the node enclosing the selection
+// from start to end.
+func RunRange(filename string, start, end int, overlay map[string][]byte) (*Doc, error) {
+	docs, err := runDocs(filename, start, end, overlay, false)
+	if err != nil {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// RunAll is like RunRange, but returns the documentation of everything
+// an ambiguous identifier denotes, most relevant first.
+func RunAll(filename string, start, end int, overlay map[string][]byte) ([]*Doc, error) {
+	return runDocs(filename, start, end, overlay, true)
+}
+
+func runDocs(filename string, start, end int, overlay map[string][]byte, all bool) ([]*Doc, error) {
+	ctx := context.Background()
+	if *loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *loadTimeout)
+		defer cancel()
+	}
+	pkg, nodes, bc, err := loadFile(ctx, filename, start, end, overlay)
 	if err != nil {
 		return nil, err
 	}
-	return DocFromNodes(pkg, nodes)
+	var doc *Doc
+	var docs []*Doc
+	file := nodes[len(nodes)-1].(*ast.File)
+	switch {
+	case end > start:
+		doc, err = SelectionDoc(pkg, nodes)
+	case *signatureHelp:
+		doc, err = SignatureDoc(pkg, nodes, cursorPos(file, start))
+	case *listMembers:
+		doc, err = MembersDoc(pkg, nodes)
+	case *exprMode:
+		doc, err = ExprDoc(pkg, nodes)
+	default:
+		if src, srcErr := readSource(filename, overlay); srcErr == nil {
+			doc = SpecDoc(src, start)
+		}
+		if doc == nil && all {
+			docs, err = DocsFromNodes(pkg, nodes)
+		} else if doc == nil {
+			doc, err = DocFromNodes(pkg, nodes)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if docs == nil {
+		docs = []*Doc{doc}
+	}
+	for _, doc := range docs {
+		if !bc.isDefault() {
+			doc.Build = bc
+		}
+		if err := finishDoc(bc, filename, pkg, doc, overlay); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// finishDoc adds the warnings and optional information requested by the
+// command line flags to doc.
+func finishDoc(bc *BuildContext, filename string, pkg *packages.Package, doc *Doc, overlay map[string][]byte) error {
+	var err error
+	if w := goVersionWarning(filename, doc.Pkg+"."+doc.Name, doc.Since); w != "" {
+		doc.Warnings = append(doc.Warnings, w)
+	}
+	if *showBody {
+		fset := pkg.Fset
+		if doc.fset != nil {
+			fset = doc.fset
+		}
+		if doc.Source, err = declSource(fset, doc.decl, overlay, *bodyLines); err != nil {
+			return err
+		}
+	}
+	if *showRefs {
+		if doc.Refs, err = findRefs(bc, filename, pkg.Fset, doc.obj, overlay); err != nil {
+			return err
+		}
+	}
+	if *showCallers || *showCallees {
+		if err := callHierarchy(bc, filename, pkg.Fset, doc, overlay); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // DocFromNodes gets the documentation from the AST node(s) in the specified package.
 func DocFromNodes(pkg *packages.Package, nodes []ast.Node) (*Doc, error) {
-	for _, node := range nodes {
+	for i, node := range nodes {
 		// log.Printf("node is a %T\n", node)
 		switch node := node.(type) {
 		case *ast.ImportSpec:
 			return PackageDoc(pkg, ImportPath(node))
 		case *ast.Ident:
+			if pkg.TypesInfo == nil {
+				if doc := syntacticDoc(pkg, node, nodes[i+1:]); doc != nil {
+					return doc, nil
+				}
+				continue
+			}
+			if sw := typeSwitch(node, nodes[i+1:]); sw != nil {
+				return typeSwitchDoc(pkg, node, sw)
+			}
 			// if we can't find the object denoted by the identifier, keep searching)
 			if obj := pkg.TypesInfo.ObjectOf(node); obj == nil {
+				// type errors can leave identifiers unresolved,
+				// so fall back to resolving them from the syntax
+				if pkg.IllTyped {
+					if doc := syntacticDoc(pkg, node, nodes[i+1:]); doc != nil {
+						return doc, nil
+					}
+				}
 				continue
 			}
-			return IdentDoc(node, pkg.TypesInfo, pkg)
+			doc, err := IdentDoc(node, pkg.TypesInfo, pkg)
+			if err != nil {
+				return nil, err
+			}
+			// show the signature of a method value or expression,
+			// which differs from the method's declaration
+			if doc.Expr, _, err = methodFunc(pkg, node, nodes[i+1:]); err != nil {
+				return nil, err
+			}
+			return doc, nil
 		default:
 			break
 		}
@@ -247,3 +562,27 @@ func parsePos(p string) (filename string, offset int, err error) {
 	off, err := strconv.ParseInt(p[sep+2:], 10, 32)
 	return filename, int(off), err
 }
+
+// parseRange parses a search position that may also be a selection,
+// of the form foo.go:#123 or foo.go:#123,#456.
+func parseRange(p string) (filename string, start, end int, err error) {
+	comma := strings.LastIndex(p, ",")
+	if comma == -1 || comma < strings.LastIndex(p, ":") {
+		filename, start, err = parsePos(p)
+		return filename, start, start, err
+	}
+	if filename, start, err = parsePos(p[:comma]); err != nil {
+		return "", 0, 0, err
+	}
+	if comma > len(p)-2 || p[comma+1] != '#' {
+		return "", 0, 0, fmt.Errorf("invalid option: -pos=%s", p)
+	}
+	off, err := strconv.ParseInt(p[comma+2:], 10, 32)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if end = int(off); end < start {
+		return "", 0, 0, fmt.Errorf("invalid option: -pos=%s (end before start)", p)
+	}
+	return filename, start, end, nil
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/scanner"
 	"go/token"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/packages/packagestest"
@@ -49,6 +56,36 @@ func TestParseInvalidPos(t *testing.T) {
 	}
 }
 
+func TestParseRange(t *testing.T) {
+	for _, test := range []struct {
+		input      string
+		start, end int
+	}{
+		{"foo.go:#123", 123, 123},
+		{"foo.go:#100,#140", 100, 140},
+		{"a,b.go:#7", 7, 7},
+	} {
+		_, start, end, err := parseRange(test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if start != test.start || end != test.end {
+			t.Errorf("%s: want %d,%d, got %d,%d", test.input, test.start, test.end, start, end)
+		}
+	}
+	for _, input := range []string{
+		"foo.go:#100,",
+		"foo.go:#100,140",
+		"foo.go:#140,#100",
+		"foo.go:100,#140",
+	} {
+		if _, _, _, err := parseRange(input); err == nil {
+			t.Errorf("expected %v to be invalid", input)
+		}
+	}
+}
+
 func TestRunInvalidPos(t *testing.T) {
 	dir := filepath.Join(".", "testdata", "package")
 	mods := []packagestest.Module{
@@ -72,6 +109,116 @@ func TestRunInvalidPos(t *testing.T) {
 	})
 }
 
+func TestRunExcludedFile(t *testing.T) {
+	files := map[string]string{
+		"a.go":         "package excluded\n\nfunc A() {}\n",
+		"ignored.go":   "//go:build ignore\n\npackage excluded\n\n// B is ignored.\nfunc B() {}\n",
+		"w_windows.go": "package excluded\n\n// W is for Windows.\nfunc W() {}\n",
+		"never.go":     "//go:build ignore && !ignore\n\npackage excluded\n\nfunc N() {}\n",
+	}
+	mods := []packagestest.Module{{Name: "excluded", Files: map[string]interface{}{}}}
+	for name, src := range files {
+		mods[0].Files[name] = src
+	}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		run := func(name, at string) (*Doc, error) {
+			return Run(exported.File("excluded", name), strings.Index(files[name], at), nil)
+		}
+
+		// files excluded in this build context are loaded in one that builds them
+		for _, test := range []struct {
+			name, at, doc, build string
+		}{
+			{"ignored.go", "B()", "B is ignored.\n", runtime.GOOS + " " + runtime.GOARCH + " [ignore]"},
+			{"w_windows.go", "W()", "W is for Windows.\n", "windows " + runtime.GOARCH + " []"},
+		} {
+			d, err := run(test.name, test.at)
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			if d.Doc != test.doc {
+				t.Errorf("%s: want doc %q, got %q", test.name, test.doc, d.Doc)
+			}
+			if d.Build == nil {
+				t.Errorf("%s: no build context reported", test.name)
+			} else if got := fmt.Sprintf("%s %s %v", d.Build.GOOS, d.Build.GOARCH, d.Build.Tags); got != test.build {
+				t.Errorf("%s: want build context %s, got %s", test.name, test.build, got)
+			}
+		}
+
+		_, err := run("never.go", "N()")
+		if err == nil {
+			t.Fatal("expected an error for a file that is never built")
+		}
+		if want := `build constraint "//go:build ignore && !ignore"`; !strings.Contains(err.Error(), want) {
+			t.Errorf("want error containing %q, got %v", want, err)
+		}
+
+		*loadTimeout = time.Nanosecond
+		defer func() { *loadTimeout = 0 }()
+		if _, err := run("a.go", "A()"); err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("want timeout error, got %v", err)
+		}
+	})
+}
+
+func TestFileNameOSArch(t *testing.T) {
+	for _, test := range []struct {
+		name, goos, goarch string
+	}{
+		{"foo.go", "", ""},
+		{"windows.go", "", ""},
+		{"foo_windows.go", "windows", ""},
+		{"foo_arm64.go", "", "arm64"},
+		{"foo_linux_arm64_test.go", "linux", "arm64"},
+		{"linux_amd64.go", "", "amd64"},
+	} {
+		goos, goarch := fileNameOSArch(test.name)
+		if goos != test.goos || goarch != test.goarch {
+			t.Errorf("%s: want %q %q, got %q %q", test.name, test.goos, test.goarch, goos, goarch)
+		}
+	}
+}
+
+func TestExcludedReason(t *testing.T) {
+	dir := t.TempDir()
+
+	other := "windows"
+	if runtime.GOOS == other {
+		other = "linux"
+	}
+	for _, test := range []struct {
+		name, src, want string
+	}{
+		{"missing.go", "", "no such file"},
+		{"notes.txt", "hello", "not a Go file"},
+		{"_skip.go", "package p", "begin with _"},
+		{"plus.go", "// +build ignore\n\npackage p", `build constraint "// +build ignore"`},
+		{"x_" + other + ".go", "package p", "file name excludes it for GOOS=" + runtime.GOOS},
+		{"ok.go", "package p", "outside the module"},
+	} {
+		filename := filepath.Join(dir, test.name)
+		if test.src != "" {
+			if err := ioutil.WriteFile(filename, []byte(test.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := excludedReason(filename, nil, &BuildContext{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}, nil); !strings.Contains(got, test.want) {
+			t.Errorf("%s: want reason containing %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
 // github.com/zmb3/gogetdoc/issues/44
 func TestInterfaceDecls(t *testing.T) {
 	mods := []packagestest.Module{
@@ -178,3 +325,224 @@ func TestIssue52(t *testing.T) {
 		}
 	})
 }
+
+func TestSameFile(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		a, b string
+		want bool
+	}{
+		{filepath.Join(dir, "a.go"), filepath.Join(link, "a.go"), true},
+		{filepath.Join(dir, "x", "..", "a.go"), filepath.Join(dir, "a.go"), true},
+		// files that exist only in the overlay
+		{filepath.Join(dir, "new.go"), filepath.Join(link, "new.go"), true},
+		{filepath.Join(dir, "newpkg", "new.go"), filepath.Join(link, "newpkg", "new.go"), true},
+		{filepath.Join(dir, "new.go"), filepath.Join(link, "other.go"), false},
+		{filepath.Join(dir, "a.go"), filepath.Join(link, "new.go"), false},
+	} {
+		if got := sameFile(test.a, test.b); got != test.want {
+			t.Errorf("sameFile(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestPackageVariant(t *testing.T) {
+	files := map[string]string{
+		"p.go":      "package p\n\n// F is in every variant.\nfunc F() {}\n",
+		"p_test.go": "package p\n\n// H is a test helper.\nfunc H() { F() }\n",
+		"x_test.go": "package p_test\n\nimport \"variant\"\n\n// X is an external test.\nfunc X() { p.F() }\n",
+	}
+	mods := []packagestest.Module{{Name: "variant", Files: map[string]interface{}{}}}
+	for name, src := range files {
+		mods[0].Files[name] = src
+	}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		defer func(v string) { *pkgVariant = v }(*pkgVariant)
+		for _, test := range []struct {
+			file, at, variant, id, err string
+		}{
+			{file: "p.go", at: "F()", id: "variant"},
+			{file: "p.go", at: "F()", variant: "normal", id: "variant"},
+			{file: "p.go", at: "F()", variant: "test", id: "variant [variant.test]"},
+			{file: "p.go", at: "F()", variant: "variant [variant.test]", id: "variant [variant.test]"},
+			{file: "p.go", at: "F()", variant: "xtest", err: `no package variant "xtest"`},
+			{file: "p_test.go", at: "H()", id: "variant [variant.test]"},
+			{file: "x_test.go", at: "X()", id: "variant_test [variant.test]"},
+			{file: "x_test.go", at: "X()", variant: "xtest", id: "variant_test [variant.test]"},
+		} {
+			*pkgVariant = test.variant
+			pkg, _, err := Load(exported.File("variant", test.file), strings.Index(files[test.file], test.at), nil)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("%s -pkg=%q: want error containing %q, got %v", test.file, test.variant, test.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s -pkg=%q: %v", test.file, test.variant, err)
+				continue
+			}
+			if pkg.ID != test.id {
+				t.Errorf("%s -pkg=%q: want package %q, got %q", test.file, test.variant, test.id, pkg.ID)
+			}
+		}
+	})
+}
+
+func TestSelectVariant(t *testing.T) {
+	normal := &packages.Package{ID: "p", Name: "p", IllTyped: true}
+	test := &packages.Package{ID: "p [p.test]", Name: "p"}
+	xtest := &packages.Package{ID: "p_test [p.test]", Name: "p_test"}
+	variants := []*packages.Package{normal, test, xtest}
+	for _, test := range []struct {
+		want string
+		i    int
+	}{
+		// by default, the first one that type-checks
+		{"", 1},
+		{"normal", 0},
+		{"test", 1},
+		{"xtest", 2},
+		{"p_test [p.test]", 2},
+	} {
+		i, err := selectVariant(variants, test.want)
+		if err != nil || i != test.i {
+			t.Errorf("selectVariant(%q) = %d, %v, want %d", test.want, i, err, test.i)
+		}
+	}
+}
+
+func TestBuildConfiguration(t *testing.T) {
+	defer func(flags, dir, goOS string, env []string) {
+		*buildFlags, *workDir, *goos, extraEnv = flags, dir, goOS, env
+	}(*buildFlags, *workDir, *goos, extraEnv)
+
+	*buildFlags = "-trimpath  -mod=vendor"
+	extraEnv = []string{"GOOS=plan9", "CGO_ENABLED=0"}
+	*workDir = "testdata"
+	*goos = ""
+	bc := defaultBuildContext()
+	dir, _ := filepath.Abs("testdata")
+	if bc.GOOS != "plan9" || bc.Dir != dir || bc.isDefault() {
+		t.Errorf("want GOOS=plan9 in %s, got %+v", dir, bc)
+	}
+	if want := []string{"-trimpath", "-mod=vendor"}; !reflect.DeepEqual(bc.buildFlags(), want) {
+		t.Errorf("want build flags %q, got %q", want, bc.buildFlags())
+	}
+	if env := bc.env(); !reflect.DeepEqual(env[len(env)-4:], []string{"GOOS=plan9", "CGO_ENABLED=0", "GOOS=plan9", "GOARCH=" + bc.GOARCH}) {
+		t.Errorf("unexpected environment %q", env[len(env)-4:])
+	}
+	if ctxt := bc.context("", nil); ctxt.CgoEnabled {
+		t.Error("want cgo disabled by -env CGO_ENABLED=0")
+	}
+	b, err := json.Marshal(&bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"flags":["-trimpath","-mod=vendor"]`, `"env":["GOOS=plan9","CGO_ENABLED=0"]`, `"dir":`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("want %s in %s", want, b)
+		}
+	}
+
+	// -goos overrides -env
+	*goos = "windows"
+	if bc := defaultBuildContext(); bc.GOOS != "windows" {
+		t.Errorf("want GOOS=windows, got %s", bc.GOOS)
+	}
+}
+
+func TestRunInDir(t *testing.T) {
+	src := "package indir\n\n// A is loaded from another directory.\nfunc A() {}\n"
+	mods := []packagestest.Module{{Name: "indir", Files: map[string]interface{}{"a.go": src}}}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+		// run from elsewhere, relying on -dir
+		if err := os.Chdir(os.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+
+		defer func(flags, dir string, env []string) {
+			*buildFlags, *workDir, extraEnv = flags, dir, env
+		}(*buildFlags, *workDir, extraEnv)
+		*buildFlags = "-trimpath"
+		*workDir = exported.Config.Dir
+		extraEnv = []string{"CGO_ENABLED=0"}
+
+		d, err := Run(exported.File("indir", "a.go"), strings.Index(src, "A()"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Import != "indir" || d.Doc != "A is loaded from another directory.\n" {
+			t.Errorf("want doc of indir.A, got %q from %q", d.Doc, d.Import)
+		}
+		if d.Build == nil || d.Build.Dir != exported.Config.Dir || len(d.Build.Flags) != 1 || len(d.Build.Env) != 1 {
+			t.Errorf("want the build settings recorded, got %+v", d.Build)
+		}
+	})
+}
+
+func TestLoadSyntaxError(t *testing.T) {
+	src := "package syntaxerr\n\n// B is declared.\nfunc B() int { return 0 }\n\nfunc A() {\n\tif B() = 1 {\n\t}\n}\n\nfunc C() int { return B() }\n"
+	_, parseErr := parser.ParseFile(token.NewFileSet(), "a.go", src, 0)
+	if parseErr == nil {
+		t.Fatal("want a syntax error")
+	}
+	wantMsg := parseErr.(scanner.ErrorList)[0].Msg
+	mods := []packagestest.Module{{Name: "syntaxerr", Files: map[string]interface{}{"a.go": src}}}
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		filename := exported.File("syntaxerr", "a.go")
+		offset := strings.LastIndex(src, "B()")
+		pkg, _, err := Load(filename, offset, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msgs []string
+		for _, e := range pkg.Errors {
+			msgs = append(msgs, e.Msg)
+		}
+		if !pkg.IllTyped || !strings.Contains(strings.Join(msgs, "\n"), wantMsg) {
+			t.Errorf("want the syntax error %q among the package errors, got %q", wantMsg, msgs)
+		}
+
+		d, err := Run(filename, offset, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Doc != "B is declared.\n" {
+			t.Errorf("want doc of B, got %q", d.Doc)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Member is a field or method accessible on an expression's type.
+type Member struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // "field" or "method"
+	Decl     string `json:"decl"`
+	Synopsis string `json:"synopsis,omitempty"`
+	Pos      string `json:"pos"`
+}
+
+// MembersDoc documents the type of the expression to the left of the
+// innermost selector in nodes, and lists the fields and methods that the
+// current package can access on it.
+func MembersDoc(pkg *packages.Package, nodes []ast.Node) (*Doc, error) {
+	var sel *ast.SelectorExpr
+	for _, node := range nodes {
+		if s, ok := node.(*ast.SelectorExpr); ok {
+			sel = s
+			break
+		}
+	}
+	if sel == nil {
+		return nil, errors.New("gogetdoc: cursor is not on a selector expression")
+	}
+	if pkg.TypesInfo == nil {
+		return nil, fmt.Errorf("gogetdoc: unknown type for %s", types.ExprString(sel.X))
+	}
+	if id, ok := sel.X.(*ast.Ident); ok {
+		if _, ok := pkg.TypesInfo.Uses[id].(*types.PkgName); ok {
+			return nil, fmt.Errorf("gogetdoc: %s is a package, not an expression", id.Name)
+		}
+	}
+	typ := pkg.TypesInfo.TypeOf(sel.X)
+	if typ == nil || typ == types.Typ[types.Invalid] {
+		return nil, fmt.Errorf("gogetdoc: unknown type for %s", types.ExprString(sel.X))
+	}
+
+	qual := qualifier(pkg.Types)
+	var d *Doc
+	if named := namedType(typ); named != nil {
+		var err error
+		if d, err = ObjectDoc(named.Obj(), pkg); err != nil {
+			return nil, err
+		}
+	} else {
+		d = &Doc{Name: types.ExprString(sel.X), Decl: types.TypeString(typ, qual)}
+	}
+
+	for _, obj := range members(typ, pkg.Types) {
+		m := Member{
+			Name:     obj.Name(),
+			Kind:     "field",
+			Decl:     types.ObjectString(obj, qual),
+			Synopsis: doc.Synopsis(declDoc(pathEnclosingInterval(pkg, obj.Pos(), obj.Pos()))),
+		}
+		if _, ok := obj.(*types.Func); ok {
+			m.Kind = "method"
+		}
+		if p := obj.Pos(); p.IsValid() {
+			m.Pos = pkg.Fset.Position(p).String()
+		}
+		d.Members = append(d.Members, m)
+	}
+	return d, nil
+}
+
+// namedType returns the named type of typ (or of the type it points to).
+func namedType(typ types.Type) *types.Named {
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	named, _ := typ.(*types.Named)
+	return named
+}
+
+// members returns the fields and methods that pkg may select on a value of
+// type typ, following embedded fields.  Fields come first, in order of
+// embedding depth, followed by methods in alphabetical order.
+func members(typ types.Type, pkg *types.Package) []types.Object {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	// breadth first through embedded fields
+	visited := make(map[types.Type]bool)
+	level := []types.Type{typ}
+	for len(level) > 0 {
+		var next []types.Type
+		for _, t := range level {
+			if p, ok := t.Underlying().(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				add(f.Name())
+				if f.Anonymous() {
+					next = append(next, f.Type())
+				}
+			}
+		}
+		level = next
+	}
+
+	// the method set of *T includes that of T
+	mtyp := typ
+	if _, ok := typ.(*types.Pointer); !ok && !types.IsInterface(typ) {
+		mtyp = types.NewPointer(typ)
+	}
+	var methods []string
+	mset := types.NewMethodSet(mtyp)
+	for i := 0; i < mset.Len(); i++ {
+		methods = append(methods, mset.At(i).Obj().Name())
+	}
+	sort.Strings(methods)
+	for _, m := range methods {
+		add(m)
+	}
+
+	var objs []types.Object
+	for _, name := range names {
+		obj, _, _ := types.LookupFieldOrMethod(typ, true, pkg, name)
+		if obj == nil || !obj.Exported() && obj.Pkg() != pkg {
+			// ambiguous, or not accessible from pkg
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
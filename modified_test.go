@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 
@@ -60,3 +63,125 @@ func TestModified(t *testing.T) {
 		}
 	})
 }
+
+func TestModifiedNewFile(t *testing.T) {
+	mods := []packagestest.Module{{Name: "unsaved", Files: map[string]interface{}{
+		"a.go": "package unsaved\n\n// A is saved.\nfunc A() {}\n",
+	}}}
+	files := map[string]string{
+		"b.go":        "package unsaved\n\n// B is unsaved.\nfunc B() { A() }\n",
+		"newpkg/c.go": "package newpkg\n\nimport \"unsaved\"\n\n// C is in an unsaved package.\nfunc C() { unsaved.A() }\n",
+	}
+
+	packagestest.TestAll(t, func(t *testing.T, exporter packagestest.Exporter) {
+		if exporter == packagestest.Modules && !modulesSupported() {
+			t.Skip("Skipping modules test on", runtime.Version())
+		}
+		exported := packagestest.Export(t, exporter, mods)
+		defer exported.Cleanup()
+
+		teardown := setup(exported.Config)
+		defer teardown()
+
+		dir := filepath.Dir(exported.File("unsaved", "a.go"))
+		overlay := make(map[string][]byte)
+		for name, src := range files {
+			overlay[filepath.Join(dir, filepath.FromSlash(name))] = []byte(src)
+		}
+
+		for _, test := range []struct {
+			file, at, name, doc string
+		}{
+			{"b.go", "B()", "B", "B is unsaved.\n"},
+			{"b.go", "A()", "A", "A is saved.\n"},
+			{"newpkg/c.go", "C()", "C", "C is in an unsaved package.\n"},
+			{"newpkg/c.go", "A()", "A", "A is saved.\n"},
+		} {
+			filename := filepath.Join(dir, filepath.FromSlash(test.file))
+			d, err := Run(filename, strings.Index(files[test.file], test.at), overlay)
+			if err != nil {
+				t.Errorf("%s %s: %v", test.file, test.at, err)
+				continue
+			}
+			if d.Name != test.name || d.Doc != test.doc {
+				t.Errorf("%s %s: want %s %q, got %s %q", test.file, test.at, test.name, test.doc, d.Name, d.Doc)
+			}
+		}
+	})
+}
+
+func TestReadOverlay(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.go.new"), "package a // from -overlay\n")
+	writeTestFile(t, filepath.Join(dir, "b.go.new"), "package b // from -overlay\n")
+	overlayJSON := fmt.Sprintf(`{"Replace": {%q: %q, %q: %q}}`,
+		"a.go", filepath.Join(dir, "a.go.new"),
+		"b.go", filepath.Join(dir, "b.go.new"))
+	writeTestFile(t, filepath.Join(dir, "overlay.json"), overlayJSON)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(m, mj bool, o string, r io.Reader) {
+		*modified, *modifiedJSON, *overlayFile, archiveReader = m, mj, o, r
+	}(*modified, *modifiedJSON, *overlayFile, archiveReader)
+
+	for _, test := range []struct {
+		name      string
+		overlay   bool
+		modified  bool
+		json      bool
+		stdin     string
+		want, err string
+	}{
+		{name: "archive", modified: true, stdin: "b.go\n26\npackage b // from archive\n", want: "b.go=package b // from archive\n"},
+		{name: "json", json: true, stdin: `{"b.go": "package b // from JSON\n"}`, want: "b.go=package b // from JSON\n"},
+		{name: "overlay", overlay: true, want: "a.go=package a // from -overlay\nb.go=package b // from -overlay\n"},
+		{name: "overlay and json", overlay: true, json: true, stdin: `{"b.go": "package b // from JSON\n"}`, want: "a.go=package a // from -overlay\nb.go=package b // from JSON\n"},
+		{name: "both stdin formats", modified: true, json: true, err: "both read standard input"},
+		{name: "bad json", json: true, stdin: `["b.go"]`, err: "invalid modified files"},
+	} {
+		*overlayFile = ""
+		if test.overlay {
+			*overlayFile = filepath.Join(dir, "overlay.json")
+		}
+		*modified, *modifiedJSON = test.modified, test.json
+		archiveReader = strings.NewReader(test.stdin)
+
+		overlay, err := readOverlay()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: want error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var names []string
+		for name := range overlay {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var got string
+		for _, name := range names {
+			// relative names are relative to the current directory
+			if filepath.Dir(name) != wd {
+				t.Errorf("%s: want %s in %s", test.name, name, wd)
+			}
+			got += filepath.Base(name) + "=" + string(overlay[name])
+		}
+		if got != test.want {
+			t.Errorf("%s: want %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestParseOverlayJSONDelete(t *testing.T) {
+	_, err := parseOverlayJSON(strings.NewReader(`{"Replace": {"a.go": ""}}`))
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("want an error for deleting a file, got %v", err)
+	}
+}
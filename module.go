@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Module describes the module that provides the documented symbol.
+type Module struct {
+	Path    string  `json:"path"`
+	Version string  `json:"version,omitempty"`
+	Replace *Module `json:"replace,omitempty"`
+
+	// Kind tells where the module's source comes from:
+	//   - "main": the main module
+	//   - "std": the standard library
+	//   - "vendor": the main module's vendor directory
+	//   - "cache": the module cache
+	//   - "workspace": another module of the go.work workspace
+	//   - "local": a local directory outside the main module,
+	//     such as the target of a replace directive
+	//   - "gopath": a GOPATH workspace (no module)
+	Kind string `json:"kind"`
+	Dir  string `json:"dir,omitempty"`
+}
+
+// moduleOf determines the module providing the package importPath whose
+// source file declFile declares the documented symbol.  from is the package
+// containing the search position, which determines the main module.
+func moduleOf(from *packages.Package, declFile, importPath string) *Module {
+	if declFile == "" {
+		return nil
+	}
+	if isStdlibFile(declFile) {
+		return &Module{
+			Path:    "std",
+			Version: goVersion(),
+			Kind:    "std",
+			Dir:     filepath.Join(build.Default.GOROOT, "src"),
+		}
+	}
+
+	var main *goMod
+	if len(from.GoFiles) > 0 {
+		if gomod := findGoMod(filepath.Dir(from.GoFiles[0])); gomod != "" {
+			main, _ = parseGoMod(gomod)
+		}
+	}
+
+	if i := strings.LastIndex(declFile, string(filepath.Separator)+"vendor"+string(filepath.Separator)); i != -1 {
+		return vendoredModule(main, declFile[:i], stripVendorFromImportPath(importPath), filepath.Dir(declFile))
+	}
+
+	if m := cachedModule(main, declFile); m != nil {
+		return m
+	}
+
+	gomod := findGoMod(filepath.Dir(declFile))
+	if gomod == "" {
+		return &Module{Path: importPath, Kind: "gopath", Dir: filepath.Dir(declFile)}
+	}
+	mod, err := parseGoMod(gomod)
+	if err != nil {
+		return nil
+	}
+	if main != nil && sameDir(mod.Dir, main.Dir) {
+		return &Module{Path: mod.Module, Kind: "main", Dir: mod.Dir}
+	}
+	if len(from.GoFiles) > 0 && inWorkspace(filepath.Dir(from.GoFiles[0]), mod.Dir) {
+		return &Module{Path: mod.Module, Kind: "workspace", Dir: mod.Dir}
+	}
+	m := &Module{Path: mod.Module, Kind: "local", Dir: mod.Dir}
+	if main == nil {
+		return m
+	}
+	for _, r := range main.Replace {
+		if !r.isDirReplacement() {
+			continue
+		}
+		dir := r.New.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(main.Dir, dir)
+		}
+		if sameDir(dir, mod.Dir) {
+			return &Module{
+				Path:    r.Old.Path,
+				Version: requiredVersion(main, r.Old),
+				Replace: &Module{Path: r.New.Path, Kind: "local", Dir: mod.Dir},
+				Kind:    "local",
+				Dir:     mod.Dir,
+			}
+		}
+	}
+	return m
+}
+
+// inWorkspace reports whether modDir is the directory of a module used by
+// the go.work workspace in effect for dir.
+func inWorkspace(dir, modDir string) bool {
+	gowork := findGoWork(dir)
+	if gowork == "" {
+		return false
+	}
+	ws, err := parseGoMod(gowork)
+	if err != nil {
+		return false
+	}
+	for _, use := range ws.Use {
+		if !filepath.IsAbs(use) {
+			use = filepath.Join(ws.Dir, use)
+		}
+		if sameDir(use, modDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// vendoredModule describes a package in the vendor directory of root.
+// Module information is read from vendor/modules.txt when present.
+func vendoredModule(main *goMod, root, importPath, dir string) *Module {
+	m := &Module{Path: importPath, Kind: "vendor", Dir: dir}
+	f, err := os.Open(filepath.Join(root, "vendor", "modules.txt"))
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// # path version [=> replacement [version]]
+		fields := strings.Fields(s.Text())
+		if len(fields) < 3 || fields[0] != "#" || !hasPathPrefix(importPath, fields[1]) {
+			continue
+		}
+		if len(fields[1]) < len(m.Path) && m.Version != "" {
+			// we already found a longer (more specific) module path
+			continue
+		}
+		m.Path, m.Version, m.Replace = fields[1], fields[2], nil
+		m.Dir = filepath.Join(root, "vendor", filepath.FromSlash(fields[1]))
+		if len(fields) >= 5 && fields[3] == "=>" {
+			m.Replace = &Module{Path: fields[4], Kind: "vendor"}
+			if len(fields) >= 6 {
+				m.Replace.Version = fields[5]
+			}
+		}
+	}
+	return m
+}
+
+// cachedModule describes a file in the module cache, or returns nil if
+// the file is not in the module cache.
+func cachedModule(main *goMod, declFile string) *Module {
+	rel, err := filepath.Rel(modCacheDir(), declFile)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	at := strings.Index(rel, "@")
+	if at < 0 {
+		return nil
+	}
+	path := unescapeModulePath(rel[:at])
+	version := rel[at+1:]
+	if i := strings.Index(version, "/"); i >= 0 {
+		version = version[:i]
+	}
+	dir := filepath.Join(modCacheDir(), filepath.FromSlash(rel[:at])+"@"+version)
+
+	m := &Module{Path: path, Version: version, Kind: "cache", Dir: dir}
+	if main == nil {
+		return m
+	}
+	for _, r := range main.Replace {
+		if r.New.Path == path && r.New.Version == version {
+			return &Module{
+				Path:    r.Old.Path,
+				Version: requiredVersion(main, r.Old),
+				Replace: m,
+				Kind:    "cache",
+				Dir:     dir,
+			}
+		}
+	}
+	return m
+}
+
+// requiredVersion returns the version of the replaced module old.
+func requiredVersion(main *goMod, old modVersion) string {
+	if old.Version != "" {
+		return old.Version
+	}
+	return main.Require[old.Path]
+}
+
+// modCacheDir returns the location of the module cache.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// unescapeModulePath reverses the case-encoding used for module paths in the
+// module cache, where upper-case letters are written as '!' followed by the
+// lower-case letter.
+func unescapeModulePath(escaped string) string {
+	var b strings.Builder
+	bang := false
+	for _, r := range escaped {
+		if r == '!' {
+			bang = true
+			continue
+		}
+		if bang {
+			r = unicode.ToUpper(r)
+			bang = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeModulePath applies the case-encoding used for module paths in the
+// module cache.
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goVersion returns the version of the Go distribution in GOROOT.
+func goVersion() string {
+	b, err := ioutil.ReadFile(filepath.Join(build.Default.GOROOT, "VERSION"))
+	if err != nil {
+		return runtime.Version()
+	}
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+}
+
+// hasPathPrefix reports whether the import path p is within the module
+// or package path prefix.
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+func sameDir(a, b string) bool {
+	if a == b {
+		return true
+	}
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(fa, fb)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const testGoMod = `module example.com/main
+
+go 1.21
+
+require (
+	example.com/dep v1.2.3 // indirect
+	example.com/local v0.1.0
+)
+
+require example.com/fork v1.0.0
+
+replace example.com/local => ../local
+
+replace (
+	example.com/fork v1.0.0 => github.com/Someone/fork v1.0.1
+)
+`
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseGoMod(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "go.mod")
+	writeTestFile(t, path, testGoMod)
+	mod, err := parseGoMod(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &goMod{
+		Dir:    dir,
+		Module: "example.com/main",
+		Go:     "1.21",
+		Require: map[string]string{
+			"example.com/dep":   "v1.2.3",
+			"example.com/local": "v0.1.0",
+			"example.com/fork":  "v1.0.0",
+		},
+		Replace: []modReplace{
+			{Old: modVersion{Path: "example.com/local"}, New: modVersion{Path: "../local"}},
+			{Old: modVersion{"example.com/fork", "v1.0.0"}, New: modVersion{"github.com/Someone/fork", "v1.0.1"}},
+		},
+	}
+	if !reflect.DeepEqual(mod, want) {
+		t.Errorf("want %+v, got %+v", want, mod)
+	}
+}
+
+func TestModuleOf(t *testing.T) {
+	dir := t.TempDir()
+
+	cache := filepath.Join(dir, "modcache")
+	t.Setenv("GOMODCACHE", cache)
+
+	mainDir := filepath.Join(dir, "main")
+	writeTestFile(t, filepath.Join(mainDir, "go.mod"), testGoMod)
+	writeTestFile(t, filepath.Join(mainDir, "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(mainDir, "vendor", "modules.txt"), "# example.com/dep v1.2.3\n## explicit\nexample.com/dep/sub\n")
+	writeTestFile(t, filepath.Join(dir, "local", "go.mod"), "module example.com/local\n")
+	from := &packages.Package{GoFiles: []string{filepath.Join(mainDir, "main.go")}}
+
+	for _, test := range []struct {
+		declFile, importPath string
+		want                 *Module
+	}{
+		{
+			filepath.Join(mainDir, "main.go"), "example.com/main",
+			&Module{Path: "example.com/main", Kind: "main", Dir: mainDir},
+		},
+		{
+			filepath.Join(mainDir, "vendor", "example.com", "dep", "sub", "sub.go"), "example.com/main/vendor/example.com/dep/sub",
+			&Module{Path: "example.com/dep", Version: "v1.2.3", Kind: "vendor", Dir: filepath.Join(mainDir, "vendor", "example.com", "dep")},
+		},
+		{
+			filepath.Join(cache, "example.com", "dep@v1.2.3", "dep.go"), "example.com/dep",
+			&Module{Path: "example.com/dep", Version: "v1.2.3", Kind: "cache", Dir: filepath.Join(cache, "example.com", "dep@v1.2.3")},
+		},
+		{
+			filepath.Join(cache, "github.com", "!someone", "fork@v1.0.1", "x", "x.go"), "github.com/Someone/fork/x",
+			&Module{
+				Path:    "example.com/fork",
+				Version: "v1.0.0",
+				Replace: &Module{Path: "github.com/Someone/fork", Version: "v1.0.1", Kind: "cache", Dir: filepath.Join(cache, "github.com", "!someone", "fork@v1.0.1")},
+				Kind:    "cache",
+				Dir:     filepath.Join(cache, "github.com", "!someone", "fork@v1.0.1"),
+			},
+		},
+		{
+			filepath.Join(dir, "local", "local.go"), "example.com/local",
+			&Module{
+				Path:    "example.com/local",
+				Version: "v0.1.0",
+				Replace: &Module{Path: "../local", Kind: "local", Dir: filepath.Join(dir, "local")},
+				Kind:    "local",
+				Dir:     filepath.Join(dir, "local"),
+			},
+		},
+	} {
+		got := moduleOf(from, test.declFile, test.importPath)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: want %+v, got %+v", test.declFile, test.want, got)
+		}
+	}
+}
+
+func TestUnescapeModulePath(t *testing.T) {
+	if got := unescapeModulePath("github.com/!burnt!sushi/toml"); got != "github.com/BurntSushi/toml" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+
+	a := "package a\n\nimport \"example.com/b\"\n\nvar _ = b.B\n"
+	files := map[string]string{
+		"go.work":  "go 1.22\n\nuse (\n\t./a\n\t\"./b\" // quoted\n)\n",
+		"a/go.mod": "module example.com/a\n\ngo 1.22\n\nrequire example.com/b v1.0.0\n",
+		"a/a.go":   a,
+		"b/go.mod": "module example.com/b\n\ngo 1.22\n",
+		"b/b.go":   "package b\n\n// B is in the workspace.\nconst B = 1\n",
+	}
+	for name, src := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), src)
+	}
+
+	ws, err := parseGoMod(filepath.Join(dir, "go.work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"./a", "./b"}; !reflect.DeepEqual(ws.Use, want) {
+		t.Errorf("want use %v, got %v", want, ws.Use)
+	}
+
+	// GOFLAGS=-mod=mod is not allowed in workspace mode,
+	// and earlier tests may have left GO111MODULE=off
+	for _, v := range []string{"GOFLAGS", "GOWORK", "GO111MODULE"} {
+		t.Setenv(v, "")
+		os.Unsetenv(v)
+	}
+	defer func(w string) { *workspace = w }(*workspace)
+	for _, test := range []struct {
+		workspace string
+		kind, err string
+	}{
+		{"", "workspace", ""},
+		{filepath.Join(dir, "go.work"), "workspace", ""},
+		// without the workspace, example.com/b can't be found
+		{"off", "", "no documentation found"},
+	} {
+		*workspace = test.workspace
+		d, err := Run(filepath.Join(dir, "a", "a.go"), strings.Index(a, "B\n"), nil)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("-workspace=%q: want error containing %q, got %v", test.workspace, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-workspace=%q: %v", test.workspace, err)
+			continue
+		}
+		want := &Module{Path: "example.com/b", Kind: test.kind, Dir: filepath.Join(dir, "b")}
+		if !strings.HasPrefix(d.Doc, "B is in the workspace.\n") || !reflect.DeepEqual(d.Module, want) {
+			t.Errorf("-workspace=%q: want %q from %+v, got %q from %+v", test.workspace, "B is in the workspace.\n", want, d.Doc, d.Module)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/go/buildutil"
+)
+
+// readOverlay returns the contents of modified files given by the -overlay,
+// -modified and -modified-json flags, keyed by absolute file name, or nil
+// if there are none.  Files read from standard input take precedence over
+// those in the -overlay file.
+func readOverlay() (map[string][]byte, error) {
+	if *modified && *modifiedJSON {
+		return nil, errors.New("-modified and -modified-json both read standard input")
+	}
+	var overlay map[string][]byte
+	if *overlayFile != "" {
+		f, err := os.Open(*overlayFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if overlay, err = parseOverlayJSON(f); err != nil {
+			return nil, fmt.Errorf("invalid overlay %s: %v", *overlayFile, err)
+		}
+	}
+	var stdin map[string][]byte
+	var err error
+	switch {
+	case *modified:
+		if stdin, err = buildutil.ParseOverlayArchive(archiveReader); err != nil {
+			return nil, fmt.Errorf("invalid archive: %v", err)
+		}
+	case *modifiedJSON:
+		if stdin, err = parseModifiedJSON(archiveReader); err != nil {
+			return nil, fmt.Errorf("invalid modified files: %v", err)
+		}
+	}
+	if stdin == nil {
+		return overlay, nil
+	}
+	if overlay == nil {
+		overlay = make(map[string][]byte, len(stdin))
+	}
+	for name, src := range stdin {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+		overlay[abs] = src
+	}
+	return overlay, nil
+}
+
+// parseOverlayJSON parses an overlay in the format of go build -overlay,
+// a JSON object whose Replace field maps file names to the names of the
+// files holding their contents, and reads those contents.  As for the go
+// command, relative file names are relative to the current directory, and
+// they are made absolute.
+func parseOverlayJSON(r io.Reader) (map[string][]byte, error) {
+	var v struct {
+		Replace map[string]string
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	overlay := make(map[string][]byte, len(v.Replace))
+	for name, contentPath := range v.Replace {
+		if contentPath == "" {
+			return nil, fmt.Errorf("cannot delete %s: deleting files is not supported", name)
+		}
+		src, err := ioutil.ReadFile(contentPath)
+		if err != nil {
+			return nil, err
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+		overlay[abs] = src
+	}
+	return overlay, nil
+}
+
+// parseModifiedJSON parses a JSON object mapping file names to their
+// contents.
+func parseModifiedJSON(r io.Reader) (map[string][]byte, error) {
+	var files map[string]string
+	if err := json.NewDecoder(r).Decode(&files); err != nil {
+		return nil, err
+	}
+	overlay := make(map[string][]byte, len(files))
+	for name, src := range files {
+		overlay[name] = []byte(src)
+	}
+	return overlay, nil
+}
@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/doc"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -24,6 +28,13 @@ func PackageDoc(from *packages.Package, importPath string) (*Doc, error) {
 	if pkg == nil {
 		return nil, fmt.Errorf("package %s not in import map of packages %v", importPath, from)
 	}
+	if len(pkg.Syntax) == 0 {
+		// the package was loaded from export data, without syntax
+		var err error
+		if pkg, err = parseImport(from, pkg.ID, parser.PackageClauseOnly|parser.ParseComments); err != nil {
+			return nil, err
+		}
+	}
 	if len(pkg.Syntax) == 0 {
 		return nil, errors.New("no documentation found for " + pkg.Name)
 	}
@@ -45,5 +56,39 @@ func PackageDoc(from *packages.Package, importPath string) (*Doc, error) {
 		Doc:    docPkg.Doc,
 		Import: importPath,
 		Pkg:    docPkg.Name,
+		Kind:   "package",
+		Module: moduleOf(from, pkg.Fset.File(pkg.Syntax[0].Pos()).Name(), pkg.PkgPath),
 	}, nil
 }
+
+// parseImport finds the package with the given ID imported by from and
+// parses its files with the given mode.  It is used for imports that
+// were loaded without syntax, from export data with -export, or that were
+// not loaded at all.
+func parseImport(from *packages.Package, id string, mode parser.Mode) (*packages.Package, error) {
+	// strip the test variant, as in "p [p.test]"
+	if i := strings.Index(id, " "); i != -1 {
+		id = id[:i]
+	}
+	bc, dir := defaultBuildContext(), ""
+	if len(from.GoFiles) > 0 {
+		bc, dir = fileBuildContext(from.GoFiles[0], nil), filepath.Dir(from.GoFiles[0])
+	}
+	pkgs, err := packages.Load(bc.config(dir, packages.LoadFiles), id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load package %s: %v", id, err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no package %s", id)
+	}
+	pkg := pkgs[0]
+	pkg.Fset = token.NewFileSet()
+	for _, name := range pkg.GoFiles {
+		f, err := parser.ParseFile(pkg.Fset, name, nil, mode)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Syntax = append(pkg.Syntax, f)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Ref is a use of the documented symbol.
+type Ref struct {
+	Pos  string `json:"pos"`
+	Pkg  string `json:"pkg"`            // import path of the package containing the use
+	Func string `json:"func,omitempty"` // enclosing function, e.g. "(*T).Method"
+}
+
+// loadModule loads all packages of the module containing filename in bc,
+// including tests, with full syntax and type information for them and
+// their dependencies.
+// Outside of a module, only the package in the directory of filename is loaded.
+func loadModule(bc *BuildContext, filename string, overlay map[string][]byte) ([]*packages.Package, error) {
+	dir := filepath.Dir(filename)
+	if gomod := findGoMod(dir); gomod != "" {
+		dir = filepath.Dir(gomod)
+	}
+	cfg := bc.config(dir, packages.LoadAllSyntax)
+	cfg.Tests = true
+	cfg.Overlay = overlay
+	pattern := "./..."
+	if bc.Dir != "" {
+		// the go command runs in -dir, not in the module
+		dir, _ = filepath.Abs(dir)
+		pattern = filepath.Join(dir, "...")
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load packages in %s: %v", dir, err)
+	}
+	return pkgs, nil
+}
+
+// findRefs finds all uses of obj in the module containing filename.
+func findRefs(bc *BuildContext, filename string, fset *token.FileSet, obj types.Object, overlay map[string][]byte) ([]Ref, error) {
+	if obj == nil {
+		return []Ref{}, nil
+	}
+	pkgs, err := loadModule(bc, filename, overlay)
+	if err != nil {
+		return nil, err
+	}
+	key := objKey(fset, obj)
+
+	type ref struct {
+		pos token.Position
+		Ref
+	}
+	var refs []ref
+	seen := make(map[token.Position]bool)
+	for _, p := range pkgs {
+		if p.TypesInfo == nil {
+			continue
+		}
+		for id, use := range p.TypesInfo.Uses {
+			if objKey(p.Fset, use) != key {
+				continue
+			}
+			pos := p.Fset.Position(id.Pos())
+			if seen[pos] {
+				// packages are loaded once more as test variants
+				continue
+			}
+			seen[pos] = true
+			refs = append(refs, ref{pos, Ref{
+				Pos:  pos.String(),
+				Pkg:  p.PkgPath,
+				Func: enclosingFuncName(p, id),
+			}})
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i].pos, refs[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	result := make([]Ref, len(refs))
+	for i := range refs {
+		result[i] = refs[i].Ref
+	}
+	return result, nil
+}
+
+// objKey identifies an object independently of the load that produced it.
+// The documented object and the objects found by loadModule come from
+// separate loads, each with its own file set, and with -export the former
+// may come from export data, which records standard library files relative
+// to $GOROOT.  So the key uses the package path and name of the object and
+// the file's base name and line of its declaration.
+func objKey(fset *token.FileSet, obj types.Object) string {
+	if obj.Pkg() == nil {
+		return "universe." + obj.Name()
+	}
+	pos := fset.Position(obj.Pos())
+	return fmt.Sprintf("%s.%s@%s:%d", obj.Pkg().Path(), obj.Name(), filepath.Base(pos.Filename), pos.Line)
+}
+
+// enclosingFuncName returns the name of the function declaration
+// containing id, or the empty string if id is outside of any function.
+func enclosingFuncName(pkg *packages.Package, id *ast.Ident) string {
+	for _, f := range pkg.Syntax {
+		if !tokenFileContainsPos(pkg.Fset.File(f.Pos()), id.Pos()) {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, id.Pos(), id.End())
+		for _, n := range path {
+			if fn, ok := n.(*ast.FuncDecl); ok {
+				return funcDeclName(fn)
+			}
+		}
+	}
+	return ""
+}
+
+// funcDeclName returns the name of a function, qualified by its receiver
+// type for methods, e.g. "(*T).Method".
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	return fmt.Sprintf("(%s).%s", types.ExprString(fn.Recv.List[0].Type), fn.Name.Name)
+}
+
+// refsSummary describes the number of references for the hover text.
+func refsSummary(refs []Ref) string {
+	pkgs := make(map[string]bool)
+	for _, r := range refs {
+		pkgs[r.Pkg] = true
+	}
+	switch {
+	case len(refs) == 0:
+		return "No references found."
+	case len(refs) == 1:
+		return "1 reference."
+	case len(pkgs) == 1:
+		return fmt.Sprintf("%d references.", len(refs))
+	}
+	return fmt.Sprintf("%d references in %d packages.", len(refs), len(pkgs))
+}
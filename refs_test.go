@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRefsSummary(t *testing.T) {
+	for _, test := range []struct {
+		refs []Ref
+		want string
+	}{
+		{nil, "No references found."},
+		{[]Ref{{Pkg: "a"}}, "1 reference."},
+		{[]Ref{{Pkg: "a"}, {Pkg: "a"}}, "2 references."},
+		{[]Ref{{Pkg: "a"}, {Pkg: "b"}, {Pkg: "a"}}, "3 references in 2 packages."},
+	} {
+		if got := refsSummary(test.refs); got != test.want {
+			t.Errorf("want %q, got %q", test.want, got)
+		}
+	}
+}
+
+func TestLoadModuleBuildContext(t *testing.T) {
+	dir := t.TempDir()
+	mod := filepath.Join(dir, "mod")
+	writeTestFile(t, filepath.Join(mod, "go.mod"), "module example.com/mod\n")
+	writeTestFile(t, filepath.Join(mod, "a.go"), "package mod\n\nfunc A() { B() }\n")
+	writeTestFile(t, filepath.Join(mod, "b.go"), "//go:build foo\n\npackage mod\n\nfunc B() {}\n")
+	writeTestFile(t, filepath.Join(mod, "c", "c.go"), "package c\n")
+
+	// -tags=foo, with the go command run from a package of the module
+	bc := defaultBuildContext()
+	bc.Tags = []string{"foo"}
+	bc.Env = []string{"GOFLAGS=-mod=mod", "GOWORK=off"}
+	bc.Dir = filepath.Join(mod, "c")
+	pkgs, err := loadModule(&bc, filepath.Join(mod, "a.go"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("want the packages of example.com/mod, got %v", pkgs)
+	}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == "example.com/mod" && (len(pkg.GoFiles) != 2 || len(pkg.Errors) != 0) {
+			t.Errorf("want example.com/mod with b.go, got %v %v", pkg.GoFiles, pkg.Errors)
+		}
+	}
+}
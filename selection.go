@@ -0,0 +1,94 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// SelectionDoc documents the smallest node enclosing a selection: the
+// callee and result type of a call, the type of a composite literal, the
+// member chosen by a selector, and otherwise the selected expression or
+// the identifier containing it.
+func SelectionDoc(pkg *packages.Package, nodes []ast.Node) (*Doc, error) {
+	switch node := nodes[0].(type) {
+	case *ast.CallExpr:
+		return callDoc(pkg, node, nodes)
+	case *ast.SelectorExpr:
+		return DocFromNodes(pkg, append([]ast.Node{node.Sel}, nodes...))
+	case *ast.Ident:
+		return DocFromNodes(pkg, nodes)
+	case ast.Expr:
+		return ExprDoc(pkg, nodes)
+	}
+	return DocFromNodes(pkg, nodes)
+}
+
+// callDoc documents the function called by call (or the type it is
+// converted to), along with the type of the call's result.
+func callDoc(pkg *packages.Package, call *ast.CallExpr, nodes []ast.Node) (*Doc, error) {
+	var tv types.TypeAndValue
+	var ok bool
+	if pkg.TypesInfo != nil {
+		tv, ok = pkg.TypesInfo.Types[call]
+	}
+	if !ok {
+		// without type information, the callee can still be resolved
+		// from the syntax
+		if path := calleePath(call, nodes); path != nil && (pkg.TypesInfo == nil || pkg.IllTyped) {
+			return DocFromNodes(pkg, path)
+		}
+		return ExprDoc(pkg, nodes)
+	}
+	result, err := newExpr(pkg, call, tv)
+	if err != nil {
+		return nil, err
+	}
+	var doc *Doc
+	if path := calleePath(call, nodes); path != nil {
+		doc, err = DocFromNodes(pkg, path)
+	}
+	if doc == nil {
+		// a call of a function literal or value without an identifier
+		doc, err = exprDoc(pkg, call, tv)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.Expr = result
+	return doc, nil
+}
+
+// calleePath returns the identifier naming the function called by call,
+// followed by its enclosing nodes, given those of call (starting with
+// call), or nil if it has none.
+func calleePath(call *ast.CallExpr, nodes []ast.Node) []ast.Node {
+	id := calleeIdent(call.Fun)
+	if id == nil {
+		return nil
+	}
+	if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok && sel.Sel == id {
+		return append([]ast.Node{id, sel}, nodes...)
+	}
+	return append([]ast.Node{id}, nodes...)
+}
+
+// calleeIdent returns the identifier naming the function in fun,
+// or nil if it has none.
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	case *ast.ParenExpr:
+		return calleeIdent(fun.X)
+	case *ast.IndexExpr:
+		return calleeIdent(fun.X)
+	case *ast.IndexListExpr:
+		return calleeIdent(fun.X)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Signature describes the function being called at the cursor,
+// for signature help.
+type Signature struct {
+	Label    string  `json:"label"` // e.g. "Printf(format string, a ...any) (n int, err error)"
+	Params   []Param `json:"params"`
+	Active   int     `json:"active"` // index of the active parameter, or -1 if there is none
+	Variadic bool    `json:"variadic,omitempty"`
+}
+
+// Param is a parameter of a function signature.
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SignatureDoc finds the innermost call whose parentheses enclose pos and
+// documents the function being called, including which of its parameters
+// the cursor is on.
+func SignatureDoc(pkg *packages.Package, nodes []ast.Node, pos token.Pos) (*Doc, error) {
+	for i, node := range nodes {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || pos <= call.Lparen || pos > call.Rparen {
+			continue
+		}
+		if pkg.TypesInfo == nil || pkg.IllTyped && pkg.TypesInfo.TypeOf(call.Fun) == nil {
+			// type errors can leave the callee untyped
+			if doc := syntacticCallDoc(pkg, call, nodes[i:], pos); doc != nil {
+				return doc, nil
+			}
+			continue
+		}
+		sig, ok := pkg.TypesInfo.TypeOf(call.Fun).(*types.Signature)
+		if !ok {
+			// a conversion, keep looking for an enclosing call
+			continue
+		}
+
+		var doc *Doc
+		var err error
+		switch fun := astutil.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			doc, err = IdentDoc(fun, pkg.TypesInfo, pkg)
+		case *ast.SelectorExpr:
+			doc, err = IdentDoc(fun.Sel, pkg.TypesInfo, pkg)
+		default:
+			doc = &Doc{Name: types.ExprString(call.Fun), Decl: types.TypeString(sig, qualifier(pkg.Types))}
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc.Signature = newSignature(doc.Name, sig, activeArg(call, pos), qualifier(pkg.Types))
+		return doc, nil
+	}
+	return nil, errors.New("gogetdoc: cursor is not inside the arguments of a function call")
+}
+
+// syntacticCallDoc documents the function called by call, whose enclosing
+// nodes (starting with call) are path, from the syntax of its declaration.
+// It returns nil if the callee is not a function declared in source.
+func syntacticCallDoc(pkg *packages.Package, call *ast.CallExpr, path []ast.Node, pos token.Pos) *Doc {
+	path = calleePath(call, path)
+	if path == nil {
+		return nil
+	}
+	doc := syntacticDoc(pkg, path[0].(*ast.Ident), path[1:])
+	if doc == nil {
+		return nil
+	}
+	fn, ok := doc.decl.(*ast.FuncDecl)
+	if !ok {
+		return nil
+	}
+	doc.Signature = syntacticSignature(doc.Name, fn.Type, activeArg(call, pos))
+	return doc
+}
+
+// activeArg returns the index of the call argument containing pos.
+func activeArg(call *ast.CallExpr, pos token.Pos) int {
+	for i, arg := range call.Args {
+		if pos <= arg.End() {
+			return i
+		}
+	}
+	return len(call.Args)
+}
+
+func newSignature(name string, sig *types.Signature, active int, qual types.Qualifier) *Signature {
+	s := &Signature{
+		Label:    name + strings.TrimPrefix(types.TypeString(sig, qual), "func"),
+		Variadic: sig.Variadic(),
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		typ := types.TypeString(p.Type(), qual)
+		if sig.Variadic() && i == params.Len()-1 {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		s.Params = append(s.Params, Param{Name: p.Name(), Type: typ})
+	}
+
+	s.Active = activeParam(active, params.Len(), sig.Variadic())
+	return s
+}
+
+// syntacticSignature is like newSignature, for a function whose type is
+// only known from its syntax.
+func syntacticSignature(name string, ft *ast.FuncType, active int) *Signature {
+	s := &Signature{Label: name + strings.TrimPrefix(types.ExprString(ft), "func")}
+	for _, field := range ft.Params.List {
+		typ := types.ExprString(field.Type)
+		if _, ok := field.Type.(*ast.Ellipsis); ok {
+			s.Variadic = true
+		}
+		if len(field.Names) == 0 {
+			s.Params = append(s.Params, Param{Type: typ})
+		}
+		for _, n := range field.Names {
+			s.Params = append(s.Params, Param{Name: n.Name, Type: typ})
+		}
+	}
+	s.Active = activeParam(active, len(s.Params), s.Variadic)
+	return s
+}
+
+// activeParam returns the index of the parameter of a function with n
+// parameters that receives call argument active, or -1 if there is none.
+func activeParam(active, n int, variadic bool) int {
+	switch {
+	case variadic && active >= n-1:
+		return n - 1
+	case active >= n:
+		return -1
+	}
+	return active
+}
+
+// qualifier qualifies types outside of pkg by their package name.
+func qualifier(pkg *types.Package) types.Qualifier {
+	return func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// specEntry documents a keyword or operator with a summary of the
+// relevant section of the Go specification.
+type specEntry struct {
+	section string // section of https://go.dev/ref/spec
+	doc     string
+}
+
+// specDocs documents Go's keywords and operators.
+var specDocs = map[string]specEntry{
+	// keywords
+	"break": {"Break statements", `A "break" statement terminates execution of the innermost "for", "switch", or "select" statement within the same function.
+If there is a label, it must be that of an enclosing "for", "switch", or "select" statement, and that is the one whose execution terminates.`},
+	"case": {"Switch statements", `A "case" clause of a "switch" or "select" statement lists the expressions, types, or communication operations to compare against.
+The statements of the first matching case are executed; there is no automatic fall through into the next case.`},
+	"chan": {"Channel types", `A channel provides a mechanism for concurrently executing functions to communicate by sending and receiving values of a specified element type.
+The optional <- operator specifies the channel direction: chan<- T can only be used to send, <-chan T only to receive.
+The value of an uninitialized channel is nil; use the built-in make to create one, optionally with a buffer capacity.`},
+	"const": {"Constant declarations", `A constant declaration binds a list of identifiers to the values of a list of constant expressions.
+Within a parenthesized const declaration list, the expression list may be omitted from any but the first ConstSpec, which repeats the previous list; together with iota this allows declaring sequences of related constants.`},
+	"continue": {"Continue statements", `A "continue" statement begins the next iteration of the innermost enclosing "for" loop by advancing control to the end of the loop block.
+If there is a label, it must be that of an enclosing "for" statement.`},
+	"default": {"Switch statements", `The "default" clause of a "switch" or "select" statement is executed if no other case matches (or, in a select, if no other communication can proceed).
+There can be at most one default clause and it may appear anywhere in the statement.`},
+	"defer": {"Defer statements", `A "defer" statement invokes a function whose execution is deferred to the moment the surrounding function returns, either because it executed a return statement, reached the end of its body, or because the goroutine is panicking.
+The function value and parameters are evaluated when the defer statement executes. Deferred functions run in last-in-first-out order and may modify named result parameters.`},
+	"else": {"If statements", `The "else" branch of an "if" statement is executed if the condition is false. It is either a block or another "if" statement.`},
+	"fallthrough": {"Fallthrough statements", `A "fallthrough" statement transfers control to the first statement of the next case clause in an expression "switch" statement.
+It may be used only as the final non-empty statement in such a clause, and not in the last clause or in a type switch.`},
+	"for": {"For statements", `A "for" statement specifies repeated execution of a block. There are three forms: the iteration may be controlled by a single condition, a "for" clause with init, condition and post statements, or a "range" clause.
+Each iteration has its own separate declared variables.`},
+	"func": {"Function declarations", `The "func" keyword declares a function or method, or introduces a function type or function literal (closure).
+A function literal can refer to variables defined in a surrounding function, which are shared between the surrounding function and the function literal.`},
+	"go": {"Go statements", `A "go" statement starts the execution of a function call as an independent concurrent thread of control, or goroutine, within the same address space.
+The function value and parameters are evaluated in the calling goroutine; unlike with a regular call, program execution does not wait for the invoked function to complete, and any return values are discarded.`},
+	"goto": {"Goto statements", `A "goto" statement transfers control to the statement with the corresponding label within the same function.
+Executing it must not cause any variables to come into scope that were not already in scope at the point of the goto, and a goto outside a block cannot jump to a label inside that block.`},
+	"if": {"If statements", `An "if" statement specifies the conditional execution of two branches according to the value of a boolean expression.
+The expression may be preceded by a simple statement, which executes before the expression is evaluated and whose variables are scoped to the if statement.`},
+	"import": {"Import declarations", `An import declaration states that the source file depends on functionality of the imported package and enables access to its exported identifiers.
+The package name may be replaced by an explicit name; the name "." imports all exported identifiers into the file block and "_" imports the package solely for its side-effects (initialization).`},
+	"interface": {"Interface types", `An interface type defines a type set. A variable of interface type can store a value of any type that is in the type set of the interface; such a type implements the interface.
+Basic interfaces list methods; general interfaces, which may only be used as type constraints, may also contain type terms such as ~int | string.`},
+	"map": {"Map types", `A map is an unordered group of elements of one type, the element type, indexed by a set of unique keys of another type, the key type.
+The comparison operators == and != must be fully defined for the key type. The value of an uninitialized map is nil; a nil map behaves like an empty map except that no elements may be added.`},
+	"package": {"Package clause", `A package clause begins each source file and defines the package to which the file belongs.
+A set of files sharing the same package name, located in the same directory, form the implementation of a package.`},
+	"range": {"For statements with range clause", `A "for" statement with a "range" clause iterates through all entries of an array, slice, string or map, values received on a channel, integer values from zero to an upper limit, or values passed to an iterator function's yield function.
+For each entry it assigns iteration values to corresponding iteration variables if present and then executes the block.`},
+	"return": {"Return statements", `A "return" statement in a function F terminates the execution of F, and optionally provides one or more result values.
+Any functions deferred by F are executed before F returns to its caller. In a function with named results, a "bare" return returns the current values of those results.`},
+	"select": {"Select statements", `A "select" statement chooses which of a set of possible send or receive operations will proceed. It looks similar to a "switch" statement but with the cases all referring to communication operations.
+If one or more of the communications can proceed, a single one is chosen via a uniform pseudo-random selection. Otherwise, if there is a default case, that case is chosen; if not, the select blocks until at least one of the communications can proceed.`},
+	"struct": {"Struct types", `A struct is a sequence of named elements, called fields, each of which has a name and a type.
+A field declared with a type but no explicit field name is an embedded field; its fields and methods are promoted to the struct. A field may be followed by an optional string literal tag.`},
+	"switch": {"Switch statements", `A "switch" statement provides multi-way execution. An expression switch compares the value of an expression against the case expressions; a type switch, written with x.(type), compares the dynamic type of an interface value against types.
+Cases are evaluated top-to-bottom and left-to-right; the first match is executed.`},
+	"type": {"Type declarations", `A type declaration binds an identifier, the type name, to a type. An alias declaration (type A = T) gives another name to an existing type, while a type definition (type T U) creates a new, distinct type with the same underlying type as U.
+In a type switch, x.(type) denotes the dynamic type of the interface value x.`},
+	"var": {"Variable declarations", `A variable declaration creates one or more variables, binds corresponding identifiers to them, and gives each a type and an initial value.
+If a type is present, each variable is given that type; otherwise each is given the (default) type of its initialization value. Without an initial value, a variable is initialized to its zero value.`},
+
+	// operators
+	"+": {"Arithmetic operators", `The + operator adds numeric operands, or concatenates strings. As a unary operator, +x is 0 + x.`},
+	"-": {"Arithmetic operators", `The - operator subtracts numeric operands. As a unary operator, -x is negation.`},
+	"*": {"Arithmetic operators", `The binary * operator multiplies numeric operands.
+As a unary operator, *x dereferences the pointer x (Address operators), and in a type, *T denotes the type of pointers to T (Pointer types).`},
+	"/": {"Arithmetic operators", `The / operator divides numeric operands. Integer division truncates towards zero; dividing an integer by zero panics at run time.`},
+	"%": {"Arithmetic operators", `The % operator computes the remainder of integer division. The result has the sign of the dividend: x == (x/y)*y + x%y.`},
+	"&": {"Address operators", `As a unary operator, &x takes the address of its operand, which must be addressable or a composite literal, yielding a pointer.
+As a binary operator, & is bitwise AND of integers (Arithmetic operators).`},
+	"|":   {"Arithmetic operators", `The | operator computes the bitwise OR of integers. In a constraint interface, it separates the terms of a union (General interfaces).`},
+	"^":   {"Arithmetic operators", `As a binary operator, ^ computes the bitwise XOR of integers. As a unary operator, ^x is the bitwise complement: for unsigned x, all bits flipped; for signed x, -x - 1.`},
+	"<<":  {"Arithmetic operators", `The << operator shifts the left operand left by the shift count specified by the right operand, which must be a non-negative integer.`},
+	">>":  {"Arithmetic operators", `The >> operator shifts the left operand right by the shift count specified by the right operand. It is an arithmetic shift if the left operand is a signed integer and a logical shift if it is unsigned.`},
+	"&^":  {"Arithmetic operators", `The &^ operator is bit clear (AND NOT): x &^ y clears the bits of x that are set in y.`},
+	"+=":  {"Assignment statements", `The assignment x += y is equivalent to x = x + y but evaluates x only once.`},
+	"-=":  {"Assignment statements", `The assignment x -= y is equivalent to x = x - y but evaluates x only once.`},
+	"*=":  {"Assignment statements", `The assignment x *= y is equivalent to x = x * y but evaluates x only once.`},
+	"/=":  {"Assignment statements", `The assignment x /= y is equivalent to x = x / y but evaluates x only once.`},
+	"%=":  {"Assignment statements", `The assignment x %= y is equivalent to x = x % y but evaluates x only once.`},
+	"&=":  {"Assignment statements", `The assignment x &= y is equivalent to x = x & y but evaluates x only once.`},
+	"|=":  {"Assignment statements", `The assignment x |= y is equivalent to x = x | y but evaluates x only once.`},
+	"^=":  {"Assignment statements", `The assignment x ^= y is equivalent to x = x ^ y but evaluates x only once.`},
+	"<<=": {"Assignment statements", `The assignment x <<= y is equivalent to x = x << y but evaluates x only once.`},
+	">>=": {"Assignment statements", `The assignment x >>= y is equivalent to x = x >> y but evaluates x only once.`},
+	"&^=": {"Assignment statements", `The assignment x &^= y is equivalent to x = x &^ y but evaluates x only once.`},
+	"&&":  {"Logical operators", `The && operator is conditional AND: the right operand is evaluated only if the left operand is true.`},
+	"||":  {"Logical operators", `The || operator is conditional OR: the right operand is evaluated only if the left operand is false.`},
+	"!":   {"Logical operators", `The unary ! operator is logical NOT.`},
+	"<-": {"Receive operator", `For an operand ch of channel type, <-ch receives a value from the channel, blocking until a value is available. Receiving from a closed channel yields the zero value after all previously sent values have been received; the form v, ok := <-ch reports whether the value was sent.
+In a send statement, ch <- v sends v on the channel (Send statements). In a channel type, <- specifies the channel direction (Channel types).`},
+	"++": {"IncDec statements", `The statement x++ increments its numeric operand by the untyped constant 1, like x += 1. It is a statement, not an expression.`},
+	"--": {"IncDec statements", `The statement x-- decrements its numeric operand by the untyped constant 1, like x -= 1. It is a statement, not an expression.`},
+	"==": {"Comparison operators", `The == operator reports whether its operands are equal. The operands must be comparable; comparing two interface values with identical dynamic types that are not comparable panics.`},
+	"!=": {"Comparison operators", `The != operator reports whether its operands are not equal. The operands must be comparable.`},
+	"<":  {"Comparison operators", `The < operator reports whether the left operand is less than the right. The operands must be ordered: integers, floats or strings.`},
+	"<=": {"Comparison operators", `The <= operator reports whether the left operand is less than or equal to the right. The operands must be ordered: integers, floats or strings.`},
+	">":  {"Comparison operators", `The > operator reports whether the left operand is greater than the right. The operands must be ordered: integers, floats or strings.`},
+	">=": {"Comparison operators", `The >= operator reports whether the left operand is greater than or equal to the right. The operands must be ordered: integers, floats or strings.`},
+	"=":  {"Assignment statements", `An assignment replaces the current values stored in variables with new values specified by expressions. The right-hand operands are evaluated before any assignment takes place.`},
+	":=": {"Short variable declarations", `A short variable declaration, x := v, declares variables with the types of their initial values. Unlike regular declarations, it may redeclare variables declared earlier in the same block, provided at least one new variable is declared.
+It may appear only inside functions.`},
+	"~": {"General interfaces", `In a constraint interface, ~T denotes the set of all types whose underlying type is T.`},
+	"...": {"Passing arguments to ... parameters", `The final parameter of a function may be variadic, ...T, and accepts zero or more arguments of type T, received as a []T.
+In a call, s... passes the slice s unchanged as the variadic argument. In an array literal, [...]T lets the compiler count the elements.`},
+}
+
+// specURL returns the URL of a section of the Go specification.
+func specURL(section string) string {
+	return "https://go.dev/ref/spec#" + strings.Replace(section, " ", "_", -1)
+}
+
+// SpecDoc documents the keyword or operator at offset in src,
+// or returns nil if there is none.
+func SpecDoc(src []byte, offset int) *Doc {
+	tok, lit := tokenAt(src, offset)
+	if lit == "" {
+		return nil
+	}
+	entry, ok := specDocs[lit]
+	if !ok {
+		return nil
+	}
+	kind := "operator"
+	if tok.IsKeyword() {
+		kind = "keyword"
+	}
+	return &Doc{
+		Name:   lit,
+		Import: "spec",
+		Pkg:    "spec",
+		Decl:   kind + " " + lit,
+		Kind:   kind,
+		Doc:    fmt.Sprintf("%s\n\nSee [%s] in the Go specification.\n\n[%[2]s]: %s\n", entry.doc, entry.section, specURL(entry.section)),
+	}
+}
+
+// tokenAt returns the keyword or operator token at offset in src.
+// It returns an empty string for other tokens, such as identifiers and literals.
+func tokenAt(src []byte, offset int) (token.Token, string) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			return tok, ""
+		}
+		start := file.Offset(pos)
+		if start > offset {
+			return token.ILLEGAL, ""
+		}
+		if !tok.IsKeyword() && !tok.IsOperator() {
+			continue
+		}
+		lit := tok.String()
+		if tok == token.SEMICOLON && src[start] != ';' {
+			// automatically inserted semicolon
+			continue
+		}
+		if offset < start+len(lit) {
+			return tok, lit
+		}
+	}
+}
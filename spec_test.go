@@ -0,0 +1,68 @@
+package main
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestSpecDocsComplete(t *testing.T) {
+	for tok := token.BREAK; tok <= token.VAR; tok++ {
+		if !tok.IsKeyword() {
+			continue
+		}
+		if _, ok := specDocs[tok.String()]; !ok {
+			t.Errorf("no documentation for keyword %s", tok)
+		}
+	}
+	for tok := token.ADD; tok <= token.TILDE; tok++ {
+		if !tok.IsOperator() {
+			continue
+		}
+		switch tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE, token.COMMA, token.PERIOD,
+			token.RPAREN, token.RBRACK, token.RBRACE, token.SEMICOLON, token.COLON:
+			// punctuation
+			continue
+		}
+		if _, ok := specDocs[tok.String()]; !ok {
+			t.Errorf("no documentation for operator %s", tok)
+		}
+	}
+}
+
+func TestSpecDoc(t *testing.T) {
+	src := []byte("package p\n\nfunc f(c chan int) {\n\tdefer close(c)\n\tc <- 1 // go\n}\n")
+	for _, test := range []struct {
+		needle, name, decl, section string
+	}{
+		{"defer", "defer", "keyword defer", "Defer statements"},
+		{"efer", "defer", "keyword defer", "Defer statements"},
+		{"chan", "chan", "keyword chan", "Channel types"},
+		{"<-", "<-", "operator <-", "Receive operator"},
+		{"- 1", "<-", "operator <-", "Receive operator"},
+		{"func", "func", "keyword func", "Function declarations"},
+	} {
+		offset := strings.Index(string(src), test.needle)
+		d := SpecDoc(src, offset)
+		if d == nil {
+			t.Errorf("%q: no documentation", test.needle)
+			continue
+		}
+		if d.Name != test.name || d.Decl != test.decl || d.Import != "spec" {
+			t.Errorf("%q: got name %q, decl %q, import %q", test.needle, d.Name, d.Decl, d.Import)
+		}
+		if kind := strings.Fields(test.decl)[0]; d.Kind != kind {
+			t.Errorf("%q: got kind %q, want %q", test.needle, d.Kind, kind)
+		}
+		if !strings.Contains(d.Doc, "["+test.section+"]: https://go.dev/ref/spec#") {
+			t.Errorf("%q: expected link to section %q in %q", test.needle, test.section, d.Doc)
+		}
+	}
+
+	for _, needle := range []string{"close", "int", "1", "// go", "(c"} {
+		if d := SpecDoc(src, strings.Index(string(src), needle)); d != nil {
+			t.Errorf("%q: expected no spec documentation, got %s", needle, d.Decl)
+		}
+	}
+}
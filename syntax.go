@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// syntacticDoc documents the identifier id, whose enclosing nodes are
+// path, without type information, for packages with type errors.  It
+// resolves id to a declaration in the same file or package, or, for a
+// selector on an imported package, to a declaration in that package.
+// It returns nil if no declaration is found.  The result is marked as
+// approximate, as the resolution can be wrong (for example, when a local
+// declaration shadows an imported package).
+func syntacticDoc(pkg *packages.Package, id *ast.Ident, path []ast.Node) *Doc {
+	file, _ := path[len(path)-1].(*ast.File)
+	if file == nil {
+		return nil
+	}
+
+	if sel, ok := path[0].(*ast.SelectorExpr); ok && sel.Sel == id {
+		x, ok := sel.X.(*ast.Ident)
+		if !ok || localDecl(x, path) != nil {
+			// a selector on a local value needs its type
+			return nil
+		}
+		imp := importedPackage(pkg, file, x.Name)
+		if imp == nil {
+			return nil
+		}
+		return packageLevelDoc(imp, id.Name)
+	}
+
+	if decl := localDecl(id, path); decl != nil {
+		return syntacticDeclDoc(pkg, file, decl)
+	}
+	return packageLevelDoc(pkg, id.Name)
+}
+
+// packageLevelDoc documents the package-level declaration of name in the
+// files of pkg, or returns nil if there is none.
+func packageLevelDoc(pkg *packages.Package, name string) *Doc {
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Name.Name == name {
+					return syntacticDeclDoc(pkg, f, decl.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if id := specIdent(spec, name); id != nil {
+						return syntacticDeclDoc(pkg, f, id)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// specIdent returns the identifier named name that spec declares, or nil.
+func specIdent(spec ast.Spec, name string) *ast.Ident {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return identNamed(name, spec.Name)
+	case *ast.ValueSpec:
+		return identNamed(name, spec.Names...)
+	}
+	return nil
+}
+
+// identNamed returns the identifier among ids named name, or nil.
+func identNamed(name string, ids ...*ast.Ident) *ast.Ident {
+	for _, id := range ids {
+		if id != nil && id.Name == name {
+			return id
+		}
+	}
+	return nil
+}
+
+// localDecl returns the identifier that declares id within the function
+// enclosing it, whose enclosing nodes are path, or nil if id is not
+// declared locally.  It looks through the scopes enclosing id from the
+// innermost out, considering only declarations that precede id.
+func localDecl(id *ast.Ident, path []ast.Node) *ast.Ident {
+	if len(path) > 0 {
+		switch n := path[0].(type) {
+		case *ast.BranchStmt:
+			if n.Label == id {
+				return labelDecl(id.Name, path)
+			}
+		case *ast.LabeledStmt:
+			if n.Label == id {
+				return id
+			}
+		}
+	}
+	// fields, params, results
+	fields := func(lists ...*ast.FieldList) *ast.Ident {
+		for _, list := range lists {
+			if list == nil {
+				continue
+			}
+			for _, f := range list.List {
+				if d := identNamed(id.Name, f.Names...); d != nil {
+					return d
+				}
+			}
+		}
+		return nil
+	}
+	// the identifiers declared by a statement, if it precedes id
+	stmt := func(s ast.Stmt) *ast.Ident {
+		if s == nil || s.Pos() > id.Pos() {
+			return nil
+		}
+		var d *ast.Ident
+		switch s := s.(type) {
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				return nil
+			}
+			for _, e := range s.Lhs {
+				if lhs, ok := e.(*ast.Ident); ok && d == nil {
+					d = identNamed(id.Name, lhs)
+				}
+			}
+		case *ast.DeclStmt:
+			if gen, ok := s.Decl.(*ast.GenDecl); ok {
+				for _, spec := range gen.Specs {
+					if d == nil {
+						d = specIdent(spec, id.Name)
+					}
+				}
+			}
+		}
+		// a variable is in scope after its declaration, except for
+		// the identifier that declares it
+		if d == nil || d != id && s.End() > id.Pos() && !isTypeDecl(s) {
+			return nil
+		}
+		return d
+	}
+	stmts := func(list []ast.Stmt) *ast.Ident {
+		for _, s := range list {
+			if d := stmt(s); d != nil {
+				return d
+			}
+		}
+		return nil
+	}
+
+	for _, n := range path {
+		var d *ast.Ident
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			d = stmts(n.List)
+		case *ast.CaseClause:
+			d = stmts(n.Body)
+		case *ast.CommClause:
+			if d = stmt(n.Comm); d == nil {
+				d = stmts(n.Body)
+			}
+		case *ast.IfStmt:
+			d = stmt(n.Init)
+		case *ast.ForStmt:
+			d = stmt(n.Init)
+		case *ast.SwitchStmt:
+			d = stmt(n.Init)
+		case *ast.TypeSwitchStmt:
+			if d = stmt(n.Init); d == nil {
+				d = stmt(n.Assign)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				key, _ := n.Key.(*ast.Ident)
+				value, _ := n.Value.(*ast.Ident)
+				if d = identNamed(id.Name, key, value); d != id && id.Pos() < n.X.End() {
+					d = nil
+				}
+			}
+		case *ast.FuncLit:
+			d = fields(n.Type.Params, n.Type.Results)
+		case *ast.FuncDecl:
+			return fields(n.Recv, n.Type.TypeParams, n.Type.Params, n.Type.Results)
+		case *ast.File:
+			return nil
+		}
+		if d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// isTypeDecl reports whether s declares types, which are in scope in
+// their own declaration.
+func isTypeDecl(s ast.Stmt) bool {
+	decl, ok := s.(*ast.DeclStmt)
+	if !ok {
+		return false
+	}
+	gen, ok := decl.Decl.(*ast.GenDecl)
+	return ok && gen.Tok == token.TYPE
+}
+
+// labelDecl returns the identifier of the statement labeled name in the
+// function enclosing path, or nil.
+func labelDecl(name string, path []ast.Node) *ast.Ident {
+	var body *ast.BlockStmt
+	for _, n := range path {
+		if fn, ok := n.(*ast.FuncLit); ok {
+			body = fn.Body
+			break
+		}
+		if fn, ok := n.(*ast.FuncDecl); ok {
+			body = fn.Body
+			break
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	var d *ast.Ident
+	ast.Inspect(body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		if s, ok := n.(*ast.LabeledStmt); ok && d == nil {
+			d = identNamed(name, s.Label)
+		}
+		return d == nil
+	})
+	return d
+}
+
+// importedPackage returns the package imported by file under the given
+// name, parsing it if it was not loaded with syntax, or nil if there is
+// no such import.
+func importedPackage(from *packages.Package, file *ast.File, name string) *packages.Package {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		imp := from.Imports[importPath]
+		switch {
+		case spec.Name != nil:
+			if spec.Name.Name != name {
+				continue
+			}
+		case imp != nil && imp.Name != "":
+			if imp.Name != name {
+				continue
+			}
+		case path.Base(importPath) != name:
+			continue
+		}
+		if imp != nil && len(imp.Syntax) > 0 {
+			return imp
+		}
+		id := importPath
+		if imp != nil && imp.ID != "" {
+			id = imp.ID
+		}
+		if imp, err = parseImport(from, id, parser.ParseComments); err == nil {
+			return imp
+		}
+		return nil
+	}
+	return nil
+}
+
+// syntacticDeclDoc documents the declaration of the identifier decl in
+// file, a file of pkg.
+func syntacticDeclDoc(pkg *packages.Package, file *ast.File, decl *ast.Ident) *Doc {
+	path, _ := astutil.PathEnclosingInterval(file, decl.Pos(), decl.End())
+	if len(path) == 0 {
+		return nil
+	}
+	return &Doc{
+		Import:      stripVendorFromImportPath(pkg.PkgPath),
+		Pkg:         pkg.Name,
+		Name:        decl.Name,
+		Decl:        syntacticDecl(pkg.Fset, decl, path),
+		Doc:         declDoc(path),
+		Pos:         pkg.Fset.Position(decl.Pos()).String(),
+		Kind:        syntacticKind(path),
+		Approximate: true,
+		decl:        sourceNode(path),
+		fset:        pkg.Fset,
+	}
+}
+
+// syntacticKind returns the kind of the declaration of the identifier
+// at the start of path, such as "func" or "var".
+func syntacticKind(path []ast.Node) string {
+	if len(path) < 2 {
+		return ""
+	}
+	switch n := path[1].(type) {
+	case *ast.FuncDecl:
+		if n.Recv != nil {
+			return "method"
+		}
+		return "func"
+	case *ast.TypeSpec:
+		return "type"
+	case *ast.ValueSpec:
+		if len(path) > 2 {
+			if gen, ok := path[2].(*ast.GenDecl); ok && gen.Tok == token.CONST {
+				return "const"
+			}
+		}
+		return "var"
+	case *ast.Field:
+		if len(path) > 3 {
+			// type parameters
+			switch t := path[3].(type) {
+			case *ast.FuncType:
+				if t.TypeParams == path[2] {
+					return "type"
+				}
+			case *ast.TypeSpec:
+				if t.TypeParams == path[2] {
+					return "type"
+				}
+			}
+		}
+		return "var"
+	case *ast.AssignStmt, *ast.RangeStmt:
+		return "var"
+	case *ast.LabeledStmt:
+		return "label"
+	}
+	return ""
+}
+
+// syntacticDecl formats the declaration of decl, whose enclosing nodes
+// are path, as well as it can without type information.
+func syntacticDecl(fset *token.FileSet, decl *ast.Ident, path []ast.Node) string {
+	format := func(node ast.Node) string {
+		var buf bytes.Buffer
+		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+		if err := cfg.Fprint(&buf, fset, node); err != nil {
+			return decl.Name
+		}
+		return stripVendorFromImportPath(buf.String())
+	}
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			cp := *n
+			cp.Doc = nil
+			cp.Body = nil
+			return format(&cp)
+		case *ast.TypeSpec:
+			cp := *n
+			if !*showUnexportedFields {
+				trimUnexportedElems(&cp)
+			}
+			cp.Doc = nil
+			cp.Comment = nil
+			return format(&ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&cp}})
+		case *ast.ValueSpec:
+			tok := token.VAR
+			if gen, ok := path[i+1].(*ast.GenDecl); ok {
+				tok = gen.Tok
+			}
+			cp := *n
+			cp.Doc = nil
+			cp.Comment = nil
+			return format(&ast.GenDecl{Tok: tok, Specs: []ast.Spec{&cp}})
+		case *ast.Field:
+			var names []string
+			for _, name := range n.Names {
+				names = append(names, name.Name)
+			}
+			return "var " + strings.Join(names, ", ") + " " + format(n.Type)
+		case *ast.AssignStmt, *ast.RangeStmt, *ast.LabeledStmt:
+			return decl.Name
+		}
+	}
+	return decl.Name
+}
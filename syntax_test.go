@@ -0,0 +1,206 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+const syntaxSrc = `package broken
+
+import (
+	"fmt"
+	str "strings"
+)
+
+// Greeting is a greeting.
+const Greeting = "hello"
+
+func use(name string) {
+	msg := Greeting + name
+	fmt.Println(msg)
+	_ = str.ToUpper(msg)
+	_ = Shout(msg)
+}
+`
+
+const syntaxSrc2 = `package broken
+
+// Shout shouts s.
+func Shout(s string) string { return s + "!" }
+`
+
+const syntaxStrings = `package strings
+
+// ToUpper upper-cases s.
+func ToUpper(s string) string
+`
+
+// syntaxPackage returns a package of syntaxSrc and syntaxSrc2 without
+// type information, as after type errors, along with the file of
+// syntaxSrc.
+func syntaxPackage(t *testing.T) (*packages.Package, *ast.File) {
+	fset := token.NewFileSet()
+	parse := func(name, src string) *ast.File {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	file := parse("a.go", syntaxSrc)
+	stringsPkg := &packages.Package{
+		ID:      "strings",
+		Name:    "strings",
+		PkgPath: "strings",
+		Fset:    fset,
+		Syntax:  []*ast.File{parse("strings.go", syntaxStrings)},
+	}
+	// a package without type information, as after type errors
+	pkg := &packages.Package{
+		Name:    "broken",
+		PkgPath: "broken",
+		Fset:    fset,
+		Syntax:  []*ast.File{file, parse("b.go", syntaxSrc2)},
+		Imports: map[string]*packages.Package{"strings": stringsPkg},
+	}
+	return pkg, file
+}
+
+// syntaxPath returns the nodes enclosing the first occurrence of at in
+// syntaxSrc, and its position.
+func syntaxPath(pkg *packages.Package, file *ast.File, at string) ([]ast.Node, token.Pos) {
+	pos := pkg.Fset.File(file.Pos()).Pos(strings.Index(syntaxSrc, at))
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	return path, pos
+}
+
+func TestSyntacticDoc(t *testing.T) {
+	pkg, file := syntaxPackage(t)
+
+	for _, test := range []struct {
+		at, pkg, decl, doc, kind string
+	}{
+		{"Greeting + name", "broken", `const Greeting = "hello"`, "Greeting is a greeting.\n", "const"},
+		{"name\n\tfmt", "broken", "var name string", "", "var"},
+		{"msg)\n\t_ = str", "broken", "msg", "", "var"},
+		{"Shout(msg)", "broken", "func Shout(s string) string", "Shout shouts s.\n", "func"},
+		{"ToUpper", "strings", "func ToUpper(s string) string", "ToUpper upper-cases s.\n", "func"},
+		{"Println", "fmt", "func Println(a ...any) (n int, err error)", "Println formats using the default formats", "func"},
+	} {
+		path, _ := syntaxPath(pkg, file, test.at)
+		d, err := DocFromNodes(pkg, path)
+		if err != nil {
+			t.Errorf("%s: %v", test.at, err)
+			continue
+		}
+		if d.Pkg != test.pkg || d.Decl != test.decl || d.Kind != test.kind {
+			t.Errorf("%s: want %s %q (%s), got %s %q (%s)", test.at, test.pkg, test.decl, test.kind, d.Pkg, d.Decl, d.Kind)
+		}
+		if !strings.HasPrefix(d.Doc, test.doc) {
+			t.Errorf("%s: want doc %q, got %q", test.at, test.doc, d.Doc)
+		}
+		if !d.Approximate {
+			t.Errorf("%s: not marked approximate", test.at)
+		}
+	}
+}
+
+func TestSyntacticModes(t *testing.T) {
+	pkg, file := syntaxPackage(t)
+
+	path, _ := syntaxPath(pkg, file, "Greeting + name")
+	docs, err := DocsFromNodes(pkg, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Name != "Greeting" || !docs[0].Approximate {
+		t.Errorf("DocsFromNodes: want Greeting, got %v", docs)
+	}
+
+	for _, test := range []struct {
+		at, label string
+		active    int
+	}{
+		{"msg)\n\t_ = str", "Println(a ...any) (n int, err error)", 0},
+		{"msg)\n\t_ = Shout", "ToUpper(s string)", 0},
+		{"msg)\n}", "Shout(s string)", 0},
+	} {
+		path, pos := syntaxPath(pkg, file, test.at)
+		d, err := SignatureDoc(pkg, path, pos)
+		if err != nil {
+			t.Errorf("%s: %v", test.at, err)
+			continue
+		}
+		if d.Signature == nil || !strings.HasPrefix(d.Signature.Label, test.label) || d.Signature.Active != test.active {
+			t.Errorf("%s: want signature %q (active %d), got %+v", test.at, test.label, test.active, d.Signature)
+		}
+	}
+
+	path, _ = syntaxPath(pkg, file, "Shout(msg)")
+	if d, err := SelectionDoc(pkg, path[1:]); err != nil || d.Name != "Shout" {
+		t.Errorf("SelectionDoc: want Shout, got %v, %v", d, err)
+	}
+	path, _ = syntaxPath(pkg, file, "ToUpper")
+	if _, err := MembersDoc(pkg, path); err == nil {
+		t.Error("MembersDoc: want an error without type information")
+	}
+	if _, err := ExprDoc(pkg, path); err == nil {
+		t.Error("ExprDoc: want an error without type information")
+	}
+}
+
+func TestLocalDecl(t *testing.T) {
+	const src = `package p
+
+func f(items []string) {
+	x := 1
+	for i, item := range items {
+		item := item + "!"
+		x := x + i
+		_ = item[x:]
+	}
+loop:
+	for {
+		break loop
+	}
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		at   string
+		line int // of the declaration, or 0 if not local
+	}{
+		{"items {", 3},
+		{"item + ", 5},
+		{"item[", 6},
+		{"x + i", 4},
+		{"x:]", 7},
+		{"i\n", 5},
+		{"loop\n\t}", 10},
+		{"string", 0},
+	} {
+		pos := fset.File(file.Pos()).Pos(strings.Index(src, test.at))
+		path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+		id, ok := path[0].(*ast.Ident)
+		if !ok {
+			t.Fatalf("%q: not an identifier", test.at)
+		}
+		line := 0
+		if d := localDecl(id, path[1:]); d != nil {
+			line = fset.Position(d.Pos()).Line
+		}
+		if line != test.line {
+			t.Errorf("%q: want declaration on line %d, got %d", test.at, test.line, line)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package somepkg
+
+// Inner is embedded in Outer.
+type Inner struct{}
+
+// Hello says hello.
+func (Inner) Hello() string { return "hello" }
+
+// Outer embeds Inner.
+type Outer struct {
+	Inner //@all("Inner", "type", "field")
+}
+
+func ambiguous(v interface{}) {
+	var o Outer
+	_ = o.Inner            //@all("Inner", "field", "type")
+	f := o.Hello           //@all("Hello", "method", "method value")
+	_ = o.Hello()          //@all("Hello", "method")
+	switch x := v.(type) { //@all("x", "var", "var", "var")
+	case int:
+		_ = x //@all("x", "var")
+	case string, bool:
+		_ = x
+	default:
+	}
+	_ = f
+}
@@ -0,0 +1,30 @@
+package somepkg
+
+// Max returns the larger of a and b.
+func Max(a, b int) int {
+	if a > b {
+		return a // a wins
+	}
+	return b
+}
+
+const (
+	// Small is small.
+	Small = 1
+	Large = 100 // Large is large.
+)
+
+type (
+	// Point is a point.
+	Point struct {
+		X, Y int // coordinates
+	}
+	Celsius float64
+)
+
+func useBody() {
+	_ = Max(Small, Large) //@body("Max", "func Max(a, b int) int {\n\tif a > b {\n\t\treturn a // a wins\n\t}\n\treturn b\n}"), body("Small", "const (\n\t// Small is small.\n\tSmall = 1\n\tLarge = 100 // Large is large.\n)")
+	_ = Point{}           //@body("Point", "type Point struct {\n\tX, Y int // coordinates\n}")
+	_ = Celsius(0)        //@body("Celsius", "type Celsius float64")
+	_ = Foo{}             //@body("Foo", "type Foo struct {\n\t// FieldA has doc\n\tFieldA string\n\tFieldB string // FieldB has a comment\n}")
+}
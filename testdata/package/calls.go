@@ -0,0 +1,26 @@
+package somepkg
+
+import "fmt"
+
+// Shape has an area.
+type Shape interface {
+	Area() float64
+}
+
+// Square is a square.
+type Square struct{ side float64 }
+
+// Area returns the area of the square.
+func (s Square) Area() float64 { return s.side * s.side } //@callers("Area", "somepkg.Describe", "somepkg.Report")
+
+// Describe prints the area of a shape.
+func Describe(s Shape) { //@callees("Describe", "(somepkg.Shape).Area", "fmt.Println")
+	fmt.Println(s.Area())
+}
+
+// Report describes a square.
+func Report() { //@callees("Report", "(somepkg.Square).Area", "somepkg.Describe"), callers("Report")
+	Describe(Square{2})
+	f := Square{3}.Area
+	_ = f
+}
@@ -0,0 +1,19 @@
+package somepkg
+
+// Offset is an offset.
+const Offset = 10
+
+// Size is a size in bytes.
+type Size int64
+
+const (
+	kilo  = 1<<10 + Offset //@expr("+", "1<<10 + Offset", "untyped int", "1034", "int")
+	greet = "a" + "b"      //@expr("+", "\"a\" + \"b\"", "untyped string", "\"ab\"", "string")
+)
+
+func exprs() {
+	_ = kilo + 1 //@expr("+", "kilo + 1", "int", "1035", "")
+	var s Size = 3
+	_ = s * 2    //@expr("* 2", "s * 2", "Size", "", ""), exprdoc("* 2", "Size is a size in bytes.")
+	_ = []int{1} //@expr("{", "[]int{1}", "[]int", "", "")
+}
@@ -0,0 +1,8 @@
+package somepkg
+
+func keywords(c chan int) { //@decl("chan", "keyword chan")
+	defer close(c)     //@decl("defer", "keyword defer"), pkg("defer", "spec")
+	for v := range c { //@decl("range", "keyword range")
+		c <- v //@decl("<-", "operator <-")
+	}
+}
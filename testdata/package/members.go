@@ -0,0 +1,29 @@
+package somepkg
+
+import "bytes"
+
+type base struct {
+	// ID identifies the record.
+	ID     int
+	secret string
+}
+
+// Touch updates the record.
+func (b *base) Touch() {}
+
+// Record is a record.
+type Record struct {
+	base
+	// Name is the name.
+	Name string
+}
+
+// Title returns the title.
+func (r Record) Title() string { return r.Name }
+
+func memberList() {
+	var r Record
+	_ = r.Name //@members("r.", "Record", "base", "Name", "ID", "secret", "Title", "Touch"), member("Name", "ID", "ID identifies the record.")
+	var buf bytes.Buffer
+	buf.Reset() //@member("buf", "Len", "Len returns the number of bytes")
+}
@@ -0,0 +1,18 @@
+package somepkg
+
+// Tally tallies.
+type Tally struct{ n int }
+
+// Add adds k to the tally and returns the total.
+func (c *Tally) Add(k int) int { c.n += k; return c.n }
+
+func methodFuncs() {
+	var c Tally
+	add := c.Add                               //@functype("Add", "func(k int) int"), doc("Add", "Add adds k to the tally")
+	addTo := (*Tally).Add                      //@functype("Add", "func(c *Tally, k int) int"), decl("Add", "func (c *Tally) Add(k int) int")
+	_ = c.Add(1) + add(2)                      //@functype("Add", "")
+	_ = addTo(&c, 3)                           //@all("addTo", "var")
+	_ = (*Tally).Add(&c, 4)                    //@functype("Add", "")
+	var f func(*Tally, int) int = (*Tally).Add //@all("Add", "method", "method expression")
+	_ = f
+}
@@ -0,0 +1,15 @@
+package somepkg
+
+// Counter counts.
+var Counter int
+
+func incr() {
+	Counter++ //@refs("Counter", "incr", "(*Foo).reset", "")
+}
+
+func (f *Foo) reset() {
+	Counter = 0
+	f.FieldA = ""
+}
+
+var _ = Counter
@@ -0,0 +1,20 @@
+package somepkg
+
+import "strings"
+
+// Vec is a vector in the plane.
+type Vec struct {
+	// X is the horizontal coordinate.
+	X, Y int
+}
+
+// Scale returns p scaled by k.
+func (p Vec) Scale(k int) Vec { return Vec{p.X * k, p.Y * k} }
+
+func selections() {
+	p := Vec{1, 2}                    //@selection("Vec{1, 2}", "Vec is a vector in the plane.", "Vec{1, 2}", "Vec")
+	_ = p.Scale(2)                    //@selection("p.Scale(2)", "Scale returns p scaled by k.", "p.Scale(2)", "Vec")
+	_ = p.X                           //@selection("p.X", "X is the horizontal coordinate.", "", "")
+	_ = strings.ToUpper("a") + "b"    //@selection("strings.ToUpper(\"a\")", "ToUpper returns s with all Unicode letters", "strings.ToUpper(\"a\")", "string")
+	_ = strings.Repeat("ab", 2) + "c" //@selection("Repeat(\"ab\", 2) + \"", "", "strings.Repeat(\"ab\", 2) + \"c\"", "string")
+}
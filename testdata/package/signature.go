@@ -0,0 +1,9 @@
+package somepkg
+
+import "fmt"
+
+func sigHelp() {
+	fmt.Printf("%d %s\n", 1, "a")   //@signature("%d", "Printf(format string, a ...any) (n int, err error)", "format"), signature(" \"a\"", "Printf(format string, a ...any) (n int, err error)", "a")
+	_ = float64(Max(len(slice), 3)) //@signature("slice", "len([]int) int", ""), signature(" 3", "Max(a int, b int) int", "b")
+	_ = Max(Max(1, 2), 4)           //@signature("2)", "Max(a int, b int) int", "b"), signature("4", "Max(a int, b int) int", "b")
+}
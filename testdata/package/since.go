@@ -0,0 +1,17 @@
+package somepkg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func since() {
+	b := fmt.Appendf(nil, "%d", 1)          //@since("Appendf", "go1.19")
+	before, _, _ := strings.Cut("a=b", "=") //@since("Cut", "go1.18")
+	var buf bytes.Buffer
+	buf.AvailableBuffer()  //@since("AvailableBuffer", "go1.21")
+	fmt.Println(b, before) //@since("Println", ""), module("Println", "std")
+}
+
+var _ = since //@module("since", "main")
@@ -0,0 +1,15 @@
+package somepkg
+
+// Kelvin is a temperature.
+type Kelvin float64
+
+func describe(v interface{}) {
+	switch t := v.(type) { //@decl("t :=", "switch t := v.(type)"), doc("t :=", "The type of t depends on the case clause:\n\n  - case Kelvin: Kelvin\n  - case int, string: interface{}\n  - default: interface{}\n")
+	case Kelvin:
+		_ = t //@decl("t", "var t Kelvin"), doc("t", "Kelvin is a temperature.")
+	case int, string:
+		_ = t //@decl("t", "var t interface{}")
+	default:
+		_ = t
+	}
+}
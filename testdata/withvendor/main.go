@@ -7,6 +7,6 @@ import (
 )
 
 func main() {
-	vp.Hello()          //@import("ello", "github.com/zmb3/vp"), doc("ello", "Hello says hello.\n")
+	vp.Hello()          //@import("ello", "github.com/zmb3/vp"), doc("ello", "Hello says hello.\n"), module("ello", "vendor")
 	fmt.Println(vp.Foo) //@decl("Foo", "const Foo untyped string")
 }
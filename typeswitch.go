@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typeSwitch returns the type switch declaring id in its guard,
+// as in switch id := x.(type), or nil.
+func typeSwitch(id *ast.Ident, path []ast.Node) *ast.TypeSwitchStmt {
+	if len(path) < 2 {
+		return nil
+	}
+	assign, ok := path[0].(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 1 || assign.Lhs[0] != id {
+		return nil
+	}
+	sw, ok := path[1].(*ast.TypeSwitchStmt)
+	if !ok || sw.Assign != assign {
+		return nil
+	}
+	return sw
+}
+
+// typeSwitchClause returns the clause of a type switch that implicitly
+// declares v, or nil if v is not such a variable.
+func typeSwitchClause(info *types.Info, v *types.Var) *ast.CaseClause {
+	for node, obj := range info.Implicits {
+		if obj == v {
+			clause, _ := node.(*ast.CaseClause)
+			return clause
+		}
+	}
+	return nil
+}
+
+// typeSwitchDoc documents the symbol declared in the guard of the type
+// switch sw, listing its type in each clause.
+func typeSwitchDoc(pkg *packages.Package, id *ast.Ident, sw *ast.TypeSwitchStmt) (*Doc, error) {
+	var guard bytes.Buffer
+	if err := printer.Fprint(&guard, pkg.Fset, sw.Assign); err != nil {
+		return nil, err
+	}
+	d := &Doc{
+		Import: pkg.PkgPath,
+		Pkg:    pkg.Name,
+		Name:   id.Name,
+		Kind:   "var",
+		Decl:   "switch " + guard.String(),
+		Pos:    pkg.Fset.Position(id.Pos()).String(),
+	}
+
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "The type of %s depends on the case clause:\n\n", id.Name)
+	qual := qualifier(pkg.Types)
+	for _, stmt := range sw.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		obj := pkg.TypesInfo.Implicits[clause]
+		if obj == nil {
+			continue
+		}
+		if d.obj == nil {
+			d.obj = obj
+		}
+		label := "default"
+		if clause.List != nil {
+			var list bytes.Buffer
+			for i, e := range clause.List {
+				if i > 0 {
+					list.WriteString(", ")
+				}
+				if err := printer.Fprint(&list, pkg.Fset, e); err != nil {
+					return nil, err
+				}
+			}
+			label = "case " + list.String()
+		}
+		fmt.Fprintf(&doc, "  - %s: %s\n", label, types.TypeString(obj.Type(), qual))
+	}
+	d.Doc = doc.String()
+	return d, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// packageVariant returns the kind of package variant pkg is: "normal",
+// "test" for a package compiled with its _test.go files (or for a test),
+// "xtest" for an external _test package, or "testmain" for the generated
+// main package of a test.
+func packageVariant(pkg *packages.Package) string {
+	switch {
+	case strings.HasSuffix(pkg.ID, ".test"):
+		return "testmain"
+	case !strings.HasSuffix(pkg.ID, ".test]"):
+		return "normal"
+	case strings.HasSuffix(pkg.Name, "_test"):
+		return "xtest"
+	}
+	return "test"
+}
+
+// selectVariant returns the index of the package variant to use among
+// variants, all of which contain the input file.  want is a kind of
+// variant, as returned by packageVariant, or a package ID; if it is empty,
+// the first variant without errors is used, or else the first.
+func selectVariant(variants []*packages.Package, want string) (int, error) {
+	if want == "" {
+		for i, pkg := range variants {
+			if !pkg.IllTyped && len(pkg.Errors) == 0 {
+				return i, nil
+			}
+		}
+		return 0, nil
+	}
+	var ids []string
+	for i, pkg := range variants {
+		if pkg.ID == want || packageVariant(pkg) == want {
+			return i, nil
+		}
+		ids = append(ids, pkg.ID)
+	}
+	return 0, fmt.Errorf("no package variant %q (have %s)", want, strings.Join(ids, ", "))
+}